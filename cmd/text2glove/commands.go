@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// subcommands — дополнительные команды: text2glove <команда> [флаги].
+// Без команды запускается предобработка корпуса.
+var subcommands = map[string]func(args []string) error{
+	"vocab":           runVocab,
+	"cooccur":         runCooccur,
+	"convert-vectors": runConvertVectors,
+	"eval":            runEval,
+	"neighbors":       runNeighbors,
+	"phrases":         runPhrases,
+	"shuffle":         runShuffle,
+	"train":           runTrain,
+}
+
+// runSubcommand выполняет команду из os.Args, если она указана.
+func runSubcommand() (handled bool, err error) {
+	if len(os.Args) < 2 {
+		return false, nil
+	}
+	run, ok := subcommands[os.Args[1]]
+	if !ok {
+		return false, nil
+	}
+	return true, run(os.Args[2:])
+}
+
+func printSubcommands() {
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(os.Stderr, "Commands: %v (run \"text2glove <command> --help\" for flags)\n", names)
+}
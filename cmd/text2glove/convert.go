@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/terratensor/text2glove/internal/vectors"
+)
+
+// runConvertVectors переводит векторы из одного формата в другой.
+func runConvertVectors(args []string) error {
+	flags := pflag.NewFlagSet("convert-vectors", pflag.ExitOnError)
+	input := flags.String("input", "vectors.txt", "Input vectors file")
+	inputFormat := flags.String("input_format", "auto", "Input format: auto|glove|word2vec|word2vec-bin|glove-bin|fasttext|t2g")
+	vocabFile := flags.String("vocab_file", "", "Vocabulary for binary GloVe vectors written by train")
+	output := flags.String("output", "", "Output vectors file")
+	outputFormat := flags.String("output_format", "auto", "Output format: auto (by extension)|glove|word2vec|word2vec-bin|fasttext|t2g")
+	maxVocab := flags.Int("max_vocab", 0, "Keep only the N most frequent words (0 = all)")
+	restrict := flags.String("restrict_vocab", "", "Keep only words listed in this file (first column; a vocab file works)")
+	normalize := flags.Bool("normalize", false, "L2-normalize vectors before export")
+	float16 := flags.Bool("float16", false, "Store values as float16 (t2g format only)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return fmt.Errorf("--output is required")
+	}
+	inFormat, err := vectors.ParseFormat(*inputFormat)
+	if err != nil {
+		return err
+	}
+	outFormat, err := outputVectorsFormat(*outputFormat, *output)
+	if err != nil {
+		return err
+	}
+	if *float16 && outFormat != vectors.FormatNative {
+		return fmt.Errorf("--float16 requires the %s output format", vectors.FormatNative)
+	}
+
+	fmt.Fprintf(os.Stderr, "=== Converting vectors ===\n")
+	fmt.Fprintf(os.Stderr, "Input: %s (%s)\n", *input, inFormat)
+	fmt.Fprintf(os.Stderr, "Output: %s (%s)\n", *output, outFormat)
+
+	startTime := time.Now()
+	e, err := vectors.Load(*input, inFormat, *vocabFile)
+	if err != nil {
+		return err
+	}
+	loaded := e.Len()
+	if *restrict != "" {
+		words, err := readWordList(*restrict)
+		if err != nil {
+			return err
+		}
+		e.Keep(func(word string) bool { return words[word] })
+	}
+	e.Restrict(*maxVocab)
+	if *normalize {
+		e.Normalize()
+	}
+	fmt.Fprintf(os.Stderr, "Vectors: %d of %d, dimension %d\n", e.Len(), loaded, e.Dim)
+
+	err = writeVectors(*output, func(f *os.File) error {
+		return vectors.Write(f, e, outFormat, vectors.WriteOptions{Float16: *float16})
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s in %v\n", *output, time.Since(startTime).Round(time.Millisecond))
+	return nil
+}
+
+// outputVectorsFormat выбирает формат вывода; auto — по расширению файла.
+func outputVectorsFormat(name, path string) (vectors.Format, error) {
+	format, err := vectors.ParseFormat(name)
+	if err != nil || format != vectors.FormatAuto {
+		return format, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".t2g":
+		return vectors.FormatNative, nil
+	case ".bin":
+		return vectors.FormatWord2VecBin, nil
+	case ".vec":
+		return vectors.FormatFastText, nil
+	}
+	return vectors.FormatGloVe, nil
+}
+
+// readWordList читает первое поле каждой строки файла.
+func readWordList(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open word list: %v", err)
+	}
+	defer file.Close()
+
+	words := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 {
+			words[fields[0]] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read word list: %v", err)
+	}
+	return words, nil
+}
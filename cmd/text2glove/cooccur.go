@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/terratensor/text2glove/internal/glove"
+)
+
+// runCooccur строит матрицу совместной встречаемости, как cooccur из GloVe.
+func runCooccur(args []string) error {
+	flags := pflag.NewFlagSet("cooccur", pflag.ExitOnError)
+	vocabFile := flags.String("vocab_file", "vocab.txt", "Vocabulary file produced by the vocab command")
+	inputs := flags.StringSlice("input", nil, "Corpus files (\"-\" for stdin, .gz supported); positional arguments are also accepted")
+	output := flags.StringP("output", "o", "cooccurrence.bin", "Output file with CREC records (\"-\" for stdout)")
+	windowSize := flags.Int("window_size", 15, "Number of context words to the left (and to the right, if symmetric)")
+	symmetric := flags.Bool("symmetric", true, "Use left and right context; false for left context only")
+	distanceWeighting := flags.Bool("distance_weighting", true, "Weight cooccurrence counts by inverse distance between words")
+	memory := flags.Float64("memory", glove.DefaultMemory, "Soft limit for memory consumption, in GB")
+	maxProduct := flags.Int64("max_product", 0, "Limit on the product of frequency ranks stored in the dense table (0 = derive from --memory)")
+	overflowLength := flags.Int64("overflow_length", 0, "Records kept in memory before spilling to disk (0 = derive from --memory)")
+	tempDir := flags.String("temp_dir", "", "Directory for overflow files (default: system temp)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	paths := append(*inputs, flags.Args()...)
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	startTime := time.Now()
+	fmt.Fprintf(os.Stderr, "=== Counting cooccurrences ===\n")
+	fmt.Fprintf(os.Stderr, "Corpus: %v\n", paths)
+	fmt.Fprintf(os.Stderr, "Window size: %d, symmetric: %v, distance weighting: %v\n", *windowSize, *symmetric, *distanceWeighting)
+
+	stats, err := glove.Cooccur(*vocabFile, paths, out, glove.CooccurOptions{
+		WindowSize:        *windowSize,
+		Symmetric:         *symmetric,
+		DistanceWeighting: *distanceWeighting,
+		Memory:            *memory,
+		MaxProduct:        *maxProduct,
+		OverflowLength:    *overflowLength,
+		TempDir:           *tempDir,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Loaded %d words, dense table contains %d elements\n", stats.VocabSize, stats.TableSize)
+	fmt.Fprintf(os.Stderr, "Processed %d tokens\n", stats.Tokens)
+	if stats.Chunks > 0 {
+		fmt.Fprintf(os.Stderr, "Merged %d overflow files\n", stats.Chunks)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d records to %s in %v\n", stats.Records, *output, time.Since(startTime).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/terratensor/text2glove/internal/eval"
+	"github.com/terratensor/text2glove/internal/vectors"
+)
+
+// runEval оценивает векторы на наборах аналогий и близости слов.
+func runEval(args []string) error {
+	flags := pflag.NewFlagSet("eval", pflag.ExitOnError)
+	vectorsFile := flags.String("vectors", "vectors.txt", "Vectors file")
+	format := flags.String("format", "auto", "Vectors format: auto|glove|word2vec|word2vec-bin|glove-bin|fasttext|t2g")
+	vocabFile := flags.String("vocab_file", "", "Vocabulary for binary GloVe vectors written by train")
+	analogies := flags.StringSlice("analogy", nil, "Analogy test sets in Google format (\": section\" lines and \"a b c d\" questions)")
+	similarities := flags.StringSlice("similarity", nil, "Word similarity datasets (\"word1 word2 score\" per line)")
+	maxVocab := flags.Int("max_vocab", 0, "Use only the N most frequent words (0 = all)")
+	lowercase := flags.Bool("lowercase", true, "Lowercase test words before lookup")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if len(*analogies) == 0 && len(*similarities) == 0 {
+		return fmt.Errorf("no test sets given: use --analogy and/or --similarity")
+	}
+	vectorsFormat, err := vectors.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	e, err := vectors.Load(*vectorsFile, vectorsFormat, *vocabFile)
+	if err != nil {
+		return err
+	}
+	e.Restrict(*maxVocab)
+	e.Normalize()
+	fmt.Fprintf(os.Stderr, "Loaded %d vectors of dimension %d in %v\n", e.Len(), e.Dim, time.Since(startTime).Round(time.Millisecond))
+
+	for _, path := range *analogies {
+		result, err := eval.Analogy(e, path, *lowercase)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n=== Analogies: %s ===\n", result.File)
+		fmt.Printf("%-32s %9s %9s %9s\n", "Section", "Accuracy", "Correct", "Coverage")
+		for _, s := range result.Sections {
+			printSection(s)
+		}
+		fmt.Println()
+		for _, s := range []eval.Section{result.Semantic, result.Syntactic, result.Total} {
+			if s.Total > 0 {
+				printSection(s)
+			}
+		}
+	}
+
+	for _, path := range *similarities {
+		result, err := eval.Similarity(e, path, *lowercase)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n=== Similarity: %s ===\n", result.File)
+		fmt.Printf("Spearman: %.4f | pairs: %d/%d (coverage %.1f%%)\n",
+			result.Spearman, result.Covered, result.Pairs, result.Coverage()*100)
+	}
+	return nil
+}
+
+func printSection(s eval.Section) {
+	fmt.Printf("%-32s %8.2f%% %4d/%-4d %8.1f%%\n", s.Name, s.Accuracy()*100, s.Correct, s.Covered, s.Coverage()*100)
+}
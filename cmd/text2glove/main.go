@@ -1,19 +1,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/terratensor/text2glove/internal/cleaner"
+	"github.com/terratensor/text2glove/internal/failures"
+	"github.com/terratensor/text2glove/internal/filter"
+	"github.com/terratensor/text2glove/internal/hyphenation"
+	"github.com/terratensor/text2glove/internal/journal"
 	"github.com/terratensor/text2glove/internal/lemmatizer"
+	"github.com/terratensor/text2glove/internal/metrics"
 	"github.com/terratensor/text2glove/internal/processor"
+	"github.com/terratensor/text2glove/internal/report"
+	"github.com/terratensor/text2glove/internal/tokenizer"
+	"github.com/terratensor/text2glove/internal/vocab"
 	"github.com/terratensor/text2glove/internal/writer"
 	"github.com/terratensor/text2glove/pkg/utils"
 
@@ -32,19 +46,70 @@ func init() {
 	pflag.Int("workers", runtime.NumCPU(), "Number of workers")
 	pflag.Int("buffer_size", 1024*1024, "Writer buffer size in bytes")
 	pflag.Int("report_every", 100, "Report progress every N files")
+	pflag.String("output_mode", "document", "Output mode: document (one document per line)|sentence (one sentence per line)")
+	pflag.String("output_format", "glove", "Output format: glove|fasttext|word2vec|linesentence")
+	pflag.String("report_json", "", "Write corpus statistics report as JSON to this file")
+	pflag.String("report_html", "", "Write corpus statistics report as a standalone HTML page to this file")
+	pflag.Int("report_top", report.DefaultTop, "Number of most frequent tokens in the report")
+	pflag.String("http_listen", "", "Serve Prometheus /metrics, /status and /debug/pprof on this address, e.g. :9090")
+	pflag.String("metadata", "", "Write per-document metadata (JSON Lines, aligned with output lines) to this file")
+	pflag.String("labels_file", "", "Document labels for fasttext format (\"file<TAB>label ...\" per line)")
+	pflag.String("default_label", "", "Label for documents missing from labels_file (empty = skip them)")
+	pflag.Int("max_line_length", 0, "Split output lines longer than N words (0 = format default: word2vec 1000, linesentence 10000)")
 	pflag.String("cleaner_mode", "unicode_letters_and_numbers", "Cleaner mode: modern|old_slavonic|all|unicode_letters")
 	pflag.Bool("normalize", true, "Apply Unicode normalization")
+	pflag.String("numbers", "", "Numbers: keep|drop|placeholder|shape|bucket (default from cleaner.keep_numbers)")
+	pflag.String("roman_numbers", "", "Roman numbers: keep|drop|placeholder|shape|bucket (default from cleaner.keep_roman_numbers)")
+	pflag.Bool("slavonic", false, "Normalize Church Slavonic text (titlo expansion, diacritics, variant letters; є, ї, ѕ only in words with Slavonic marks or letters)")
+	pflag.String("slavonic_dict", "", "Extra titlo abbreviations file (\"abbreviation expansion\" per line)")
+	pflag.Bool("dehyphenate", false, "Rejoin words hyphenated across line breaks")
+	pflag.String("hyphenation_dict", "", "Word list used to check rejoined words (one word per line)")
+	pflag.StringSlice("stopwords", nil, "Built-in stopword lists to apply: ru,en")
+	pflag.String("stopwords_file", "", "Extra stopwords file (one word per line)")
+	pflag.Int("min_token_length", 0, "Drop tokens shorter than N runes (0 = no limit)")
+	pflag.Int("max_token_length", 0, "Drop tokens longer than N runes (0 = no limit; 100 when lemmatizing, unless set)")
+	pflag.String("subsample_vocab", "", "Vocabulary with word counts for frequent-word subsampling (from the vocab command)")
+	pflag.Float64("sample", 0, "Subsampling threshold, e.g. 1e-3 .. 1e-5 (0 = disabled)")
+	pflag.Int64("subsample_seed", 1, "Random seed for subsampling")
+	pflag.Int("shard_mb", 0, "Split output into shards of about N MB of uncompressed text (0 = single file)")
+	pflag.Int("shard_docs", 0, "Split output into shards of N documents (0 = no limit)")
+	pflag.String("compression", "none", "Output compression: none|gzip|zstd")
+	pflag.Float64Slice("split", nil, "Split documents into train,valid,test outputs by these ratios, e.g. 0.98,0.01,0.01")
+	pflag.Int64("split_seed", 0, "Seed of the document hash used for the split")
+	pflag.Bool("ordered", false, "Write documents in input order regardless of worker scheduling (with --dehyphenate, words are counted per document, so joins and output differ from unordered runs)")
+	pflag.Int("reorder_buffer", writer.DefaultReorderBuffer, "Documents that may wait for their turn in ordered mode")
+	pflag.Bool("resume", false, "Resume an interrupted run: skip files listed in the journal and append to the output")
+	pflag.Bool("no_journal", false, "Do not keep a journal of processed files")
+	pflag.String("journal", "", "Journal path (default <output>.journal)")
+	pflag.Int("journal_interval", 30, "Save the journal every N seconds")
+	pflag.String("on_error", "skip", "Error policy for failed files: skip|fail-fast|retry")
+	pflag.Int("retries", 3, "Retries per file with --on_error retry")
+	pflag.Int("max_errors", 0, "Stop with a non-zero exit status when more than N files fail (0 = no limit)")
+	pflag.String("error_report", "", "Write failed files as JSON Lines to this path")
 	pflag.Bool("lemmatize", false, "Enable lemmatization with mystem")
 	pflag.String("mystem_path", "", "Path to mystem binary (default: look in PATH)")
 	pflag.String("mystem_flags", "-ld", "Mystem flags")
 }
 
 func main() {
+	if handled, err := runSubcommand(); handled {
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	pflag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of text2glove:\n")
+		pflag.PrintDefaults()
+		printSubcommands()
+	}
 	pflag.Parse()
 
 	// 1. Инициализация Viper с явными значениями по умолчанию
 	v := viper.New()
 	v.SetDefault("lemmatization.enable", false)
+	v.SetDefault("journal.enable", true)
 	v.SetDefault("lemmatization.mystem_path", "")
 	v.SetDefault("lemmatization.mystem_flags", "-ld")
 
@@ -68,9 +133,64 @@ func main() {
 		WorkersCount: v.GetInt("workers"),
 		BufferSize:   v.GetInt("buffer_size"),
 		ReportEvery:  v.GetInt("report_every"),
+		OutputMode:   v.GetString("output_mode"),
+		OutputFormat: v.GetString("output_format"),
+		Metadata:     v.GetString("metadata"),
+	}
+	switch config.OutputMode {
+	case "document", "sentence":
+	default:
+		log.Fatalf("Unknown output mode %q: expected document|sentence", config.OutputMode)
+	}
+	config.Report.JSON = v.GetString("report_json")
+	if config.Report.JSON == "" {
+		config.Report.JSON = v.GetString("report.json")
+	}
+	config.Report.HTML = v.GetString("report_html")
+	if config.Report.HTML == "" {
+		config.Report.HTML = v.GetString("report.html")
+	}
+	config.Report.Top = intSetting(v, "report_top", "report.top")
+	config.HTTP.Listen = v.GetString("http_listen")
+	if config.HTTP.Listen == "" {
+		config.HTTP.Listen = v.GetString("http.listen")
+	}
+	config.Sharding.MaxMB = intSetting(v, "shard_mb", "sharding.max_mb")
+	config.Sharding.MaxDocs = intSetting(v, "shard_docs", "sharding.max_docs")
+	config.Sharding.Compression = v.GetString("compression")
+	if !pflag.CommandLine.Changed("compression") && v.IsSet("sharding.compression") {
+		config.Sharding.Compression = v.GetString("sharding.compression")
 	}
+	config.Split.Ratios = floatsSetting(v, "split", "split.ratios")
+	config.Split.Seed = int64(intSetting(v, "split_seed", "split.seed"))
+	config.Ordering.Enable = v.GetBool("ordered") || v.GetBool("ordering.enable")
+	config.Ordering.ReorderBuffer = intSetting(v, "reorder_buffer", "ordering.reorder_buffer")
+	config.Format.LabelsFile = v.GetString("labels_file")
+	if config.Format.LabelsFile == "" {
+		config.Format.LabelsFile = v.GetString("format.labels_file")
+	}
+	config.Format.DefaultLabel = v.GetString("default_label")
+	if config.Format.DefaultLabel == "" {
+		config.Format.DefaultLabel = v.GetString("format.default_label")
+	}
+	config.Format.MaxLineLength = intSetting(v, "max_line_length", "format.max_line_length")
 	config.Cleaner.Mode = v.GetString("cleaner_mode")
 	config.Cleaner.Normalize = v.GetBool("normalize")
+	config.Cleaner.KeepNumbers = v.GetBool("cleaner.keep_numbers")
+	config.Cleaner.KeepRomanNumbers = v.GetBool("cleaner.keep_roman_numbers")
+	config.Cleaner.Numbers = numberStrategySetting(v, "numbers", config.Cleaner.KeepNumbers)
+	config.Cleaner.RomanNumbers = numberStrategySetting(v, "roman_numbers", config.Cleaner.KeepRomanNumbers)
+	config.Cleaner.Slavonic = v.GetBool("slavonic") || v.GetBool("cleaner.slavonic")
+	config.Cleaner.SlavonicDict = v.GetString("slavonic_dict")
+	if config.Cleaner.SlavonicDict == "" {
+		config.Cleaner.SlavonicDict = v.GetString("cleaner.slavonic_dict")
+	}
+	config.Hyphenation.Enable = v.GetBool("dehyphenate") || v.GetBool("hyphenation.enable")
+	config.Hyphenation.Dictionary = v.GetString("hyphenation_dict")
+	if config.Hyphenation.Dictionary == "" {
+		config.Hyphenation.Dictionary = v.GetString("hyphenation.dictionary")
+	}
+	config.Hyphenation.MaxWords = v.GetInt("hyphenation.max_words")
 	config.Lemmatization.Enable = v.GetBool("lemmatize") || v.GetBool("lemmatization.enable")
 	config.Lemmatization.MystemPath = v.GetString("mystem_path")
 	if config.Lemmatization.MystemPath == "" {
@@ -90,6 +210,56 @@ func main() {
 		}
 	}
 
+	config.Filter.Stopwords = v.GetStringSlice("stopwords")
+	if len(config.Filter.Stopwords) == 0 {
+		config.Filter.Stopwords = v.GetStringSlice("filter.stopwords")
+	}
+	config.Filter.StopwordsFile = v.GetString("stopwords_file")
+	if config.Filter.StopwordsFile == "" {
+		config.Filter.StopwordsFile = v.GetString("filter.stopwords_file")
+	}
+	config.Subsampling.Vocab = v.GetString("subsample_vocab")
+	if config.Subsampling.Vocab == "" {
+		config.Subsampling.Vocab = v.GetString("subsampling.vocab")
+	}
+	config.Subsampling.Sample = v.GetFloat64("sample")
+	if !pflag.CommandLine.Changed("sample") && v.IsSet("subsampling.sample") {
+		config.Subsampling.Sample = v.GetFloat64("subsampling.sample")
+	}
+	config.Subsampling.Seed = int64(intSetting(v, "subsample_seed", "subsampling.seed"))
+	config.Filter.MinLength = intSetting(v, "min_token_length", "filter.min_length")
+	config.Filter.MaxLength = intSetting(v, "max_token_length", "filter.max_length")
+	// Без явной настройки длинные токены отсекаются только перед mystem,
+	// как и раньше: вывод без лемматизации не меняется
+	if config.Lemmatization.Enable && !pflag.CommandLine.Changed("max_token_length") && !v.IsSet("filter.max_length") {
+		config.Filter.MaxLength = filter.LemmatizedMaxLength
+	}
+
+	config.Journal.Enable = v.GetBool("journal.enable") && !v.GetBool("no_journal")
+	config.Journal.Path = v.GetString("journal")
+	if config.Journal.Path == "" {
+		config.Journal.Path = v.GetString("journal.path")
+	}
+	if config.Journal.Path == "" {
+		config.Journal.Path = journal.DefaultPath(v.GetString("output"))
+	}
+	config.Journal.Interval = intSetting(v, "journal_interval", "journal.interval")
+	config.Journal.Resume = v.GetBool("resume")
+	if config.Journal.Resume && !config.Journal.Enable {
+		log.Fatal("--resume needs the journal: remove --no_journal")
+	}
+
+	config.Errors.Policy = v.GetString("on_error")
+	if !pflag.CommandLine.Changed("on_error") && v.IsSet("errors.policy") {
+		config.Errors.Policy = v.GetString("errors.policy")
+	}
+	config.Errors.Retries = intSetting(v, "retries", "errors.retries")
+	config.Errors.MaxErrors = intSetting(v, "max_errors", "errors.max_errors")
+	config.Errors.Report = v.GetString("error_report")
+	if config.Errors.Report == "" {
+		config.Errors.Report = v.GetString("errors.report")
+	}
+
 	// Добавляем чтение настроек логгера
 	config.Logger.Enabled = v.GetBool("logger.enabled")
 	config.Logger.LongWordsLog = v.GetString("logger.long_words_log")
@@ -97,6 +267,47 @@ func main() {
 	startPipeline(config)
 }
 
+// intSetting читает целое значение: явно заданный флаг, затем ключ
+// конфига, затем значение флага по умолчанию.
+func intSetting(v *viper.Viper, flag, key string) int {
+	if !pflag.CommandLine.Changed(flag) && v.IsSet(key) {
+		return v.GetInt(key)
+	}
+	return v.GetInt(flag)
+}
+
+// floatsSetting читает список чисел: флаг, затем key из конфига.
+func floatsSetting(v *viper.Viper, flag, key string) []float64 {
+	if pflag.CommandLine.Changed(flag) {
+		values, _ := pflag.CommandLine.GetFloat64Slice(flag)
+		return values
+	}
+	var values []float64
+	for _, item := range v.GetStringSlice(key) {
+		value, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+		if err != nil {
+			log.Fatalf("Invalid number in %s: %q", key, item)
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// numberStrategySetting читает стратегию обработки чисел: флаг, затем
+// cleaner.<key> из конфига, затем устаревший флаг keep_* (keep или drop).
+func numberStrategySetting(v *viper.Viper, key string, keep bool) string {
+	if s := v.GetString(key); s != "" {
+		return s
+	}
+	if s := v.GetString("cleaner." + key); s != "" {
+		return s
+	}
+	if keep {
+		return string(cleaner.NumbersKeep)
+	}
+	return string(cleaner.NumbersDrop)
+}
+
 func startPipeline(config utils.Config) {
 	startTime := time.Now()
 
@@ -107,8 +318,24 @@ func startPipeline(config utils.Config) {
 	fmt.Printf("Input directory: %s\n", config.InputDir)
 	fmt.Printf("Output file: %s\n", config.OutputFile)
 	fmt.Printf("Number of workers: %v\n", config.WorkersCount)
+	fmt.Printf("Output mode: %s\n", config.OutputMode)
+	fmt.Printf("Output format: %s\n", config.OutputFormat)
+	if config.Ordering.Enable {
+		fmt.Printf("Ordered output: reorder buffer %d documents\n", config.Ordering.ReorderBuffer)
+		if config.Hyphenation.Enable {
+			fmt.Printf("Hyphenation rejoin: word frequencies are counted per document\n")
+		}
+	}
 	fmt.Printf("Cleaner mode: %s\n", config.Cleaner.Mode)
+	fmt.Printf("Numbers: %s, roman numbers: %s\n", config.Cleaner.Numbers, config.Cleaner.RomanNumbers)
 	fmt.Printf("Unicode normalization: %v\n", config.Cleaner.Normalize)
+	fmt.Printf("Slavonic normalization: %v\n", config.Cleaner.Slavonic)
+	fmt.Printf("Hyphenation rejoin: %v\n", config.Hyphenation.Enable)
+	fmt.Printf("Stopwords: %v (file: %q)\n", config.Filter.Stopwords, config.Filter.StopwordsFile)
+	fmt.Printf("Token length: min %d, max %d\n", config.Filter.MinLength, config.Filter.MaxLength)
+	if config.Subsampling.Sample > 0 {
+		fmt.Printf("Subsampling: sample %g, vocabulary %s, seed %d\n", config.Subsampling.Sample, config.Subsampling.Vocab, config.Subsampling.Seed)
+	}
 	fmt.Printf("Lemmatization enabled: %v\n", config.Lemmatization.Enable)
 	fmt.Printf("Logger enabled: %v\n", config.Logger.Enabled)
 	fmt.Printf("Long words log: %v\n", config.Logger.LongWordsLog)
@@ -118,9 +345,27 @@ func startPipeline(config utils.Config) {
 	}
 
 	// Инициализация cleaner с опциями
+	numbers, err := cleaner.ParseNumberStrategy(config.Cleaner.Numbers)
+	if err != nil {
+		log.Fatalf("Invalid numbers setting: %v", err)
+	}
+	romanNumbers, err := cleaner.ParseNumberStrategy(config.Cleaner.RomanNumbers)
+	if err != nil {
+		log.Fatalf("Invalid roman_numbers setting: %v", err)
+	}
+
 	cleanOptions := cleaner.CleanOptions{
-		KeepNumbers:      config.Cleaner.KeepNumbers,      // из конфига
-		KeepRomanNumbers: config.Cleaner.KeepRomanNumbers, // из конфига
+		Numbers:           numbers,      // из конфига
+		RomanNumbers:      romanNumbers, // из конфига
+		NormalizeSlavonic: config.Cleaner.Slavonic,
+	}
+
+	if config.Cleaner.Slavonic && config.Cleaner.SlavonicDict != "" {
+		abbreviations, err := cleaner.LoadAbbreviations(config.Cleaner.SlavonicDict)
+		if err != nil {
+			log.Fatalf("Failed to load slavonic abbreviations: %v", err)
+		}
+		cleanOptions.SlavonicAbbreviations = abbreviations
 	}
 
 	textCleaner := cleaner.New(
@@ -128,34 +373,296 @@ func startPipeline(config utils.Config) {
 		cleanOptions,
 	)
 
-	// Инициализация лемматизатора с логгером
+	// Инициализация лемматизатора
 	var lem *lemmatizer.Lemmatizer
-	var err error
 	if config.Lemmatization.Enable {
-		lem, err = lemmatizer.New(
-			config.Lemmatization.MystemPath,
-			config.Lemmatization.MystemFlags,
-			config.Logger.Enabled,      // передаем флаг включения логирования
-			config.Logger.LongWordsLog, // передаем путь к лог-файлу
-		)
+		lem = lemmatizer.New(config.Lemmatization.MystemPath, config.Lemmatization.MystemFlags)
+	}
+
+	// Фильтр стоп-слов и длины токенов; отброшенные длинные токены пишутся в лог
+	stopwords, err := filter.BuiltinStopwords(config.Filter.Stopwords...)
+	if err != nil {
+		log.Fatalf("Failed to load stopwords: %v", err)
+	}
+	if config.Filter.StopwordsFile != "" {
+		if err := filter.LoadStopwords(config.Filter.StopwordsFile, stopwords); err != nil {
+			log.Fatalf("Failed to load stopwords: %v", err)
+		}
+	}
+	var longWordsLog string
+	if config.Logger.Enabled {
+		longWordsLog = config.Logger.LongWordsLog
+	}
+	tokenFilter, err := filter.New(config.Filter.MinLength, config.Filter.MaxLength, stopwords, longWordsLog)
+	if err != nil {
+		log.Fatalf("Failed to initialize token filter: %v", err)
+	}
+	defer tokenFilter.Close()
+
+	// Склейка переносов
+	var joiner *hyphenation.Joiner
+	if config.Hyphenation.Enable {
+		var dictionary map[string]struct{}
+		if config.Hyphenation.Dictionary != "" {
+			dictionary, err = hyphenation.LoadDictionary(config.Hyphenation.Dictionary)
+			if err != nil {
+				log.Fatalf("Failed to load hyphenation dictionary: %v", err)
+			}
+		}
+		// При упорядоченном выводе частоты набираются по документу, чтобы
+		// склейка не зависела от того, какие файлы рабочие обработали раньше
+		joiner = hyphenation.New(dictionary, config.Hyphenation.MaxWords, config.Ordering.Enable)
+	}
+
+	// Формат вывода
+	format, err := writer.ParseFormat(config.OutputFormat)
+	if err != nil {
+		log.Fatalf("Invalid output_format setting: %v", err)
+	}
+	writerOptions := writer.Options{
+		Format:        format,
+		DefaultLabel:  config.Format.DefaultLabel,
+		MaxLineLength: config.Format.MaxLineLength,
+	}
+	layout := writer.Layout{
+		Ordered:       config.Ordering.Enable,
+		ReorderBuffer: config.Ordering.ReorderBuffer,
+		MetadataPath:  config.Metadata,
+	}
+	// Статистика корпуса для отчёта; reportSink остаётся nil-интерфейсом,
+	// если отчёт не нужен
+	var collector *report.Collector
+	var reportSink writer.Collector
+	if config.Report.JSON != "" || config.Report.HTML != "" {
+		collector = report.NewCollector(config.Report.Top)
+		reportSink = collector
+	}
+	compression, err := writer.ParseCompression(config.Sharding.Compression)
+	if err != nil {
+		log.Fatalf("Invalid compression setting: %v", err)
+	}
+	layout.Sharding = writer.Sharding{
+		MaxBytes:    int64(config.Sharding.MaxMB) * 1024 * 1024,
+		MaxDocs:     config.Sharding.MaxDocs,
+		Compression: compression,
+	}
+	if len(config.Split.Ratios) > 0 {
+		if layout.Split, err = writer.NewSplit(config.Split.Ratios, config.Split.Seed); err != nil {
+			log.Fatalf("Invalid split setting: %v", err)
+		}
+	}
+	if (layout.Sharding.Enabled() || layout.Split != nil) && config.Journal.Enable {
+		if config.Journal.Resume {
+			log.Fatal("--resume works only with a single uncompressed output file")
+		}
+		fmt.Println("Journal disabled: resume works only with a single uncompressed output file")
+		config.Journal.Enable = false
+	}
+	if config.Format.LabelsFile != "" {
+		if writerOptions.Labels, err = writer.LoadLabels(config.Format.LabelsFile); err != nil {
+			log.Fatalf("Failed to load labels: %v", err)
+		}
+	}
+	if config.Subsampling.Sample > 0 {
+		if config.Subsampling.Vocab == "" {
+			log.Fatal("Subsampling needs word counts: run \"text2glove vocab\" and pass --subsample_vocab")
+		}
+		entries, err := vocab.Read(config.Subsampling.Vocab)
 		if err != nil {
-			log.Fatalf("Failed to initialize lemmatizer: %v", err)
+			log.Fatalf("Failed to load subsampling vocabulary: %v", err)
 		}
-		defer lem.Close()
+		writerOptions.Subsampler = writer.NewSubsampler(entries, config.Subsampling.Sample, config.Subsampling.Seed)
+	}
+	if format == writer.FormatFastText && writerOptions.Labels == nil && writerOptions.DefaultLabel == "" {
+		log.Fatal("fasttext format needs --labels_file or --default_label")
 	}
 
-	fileProcessor := processor.New(textCleaner, lem, config.Lemmatization.Enable)
-	resultWriter := writer.New(config.OutputFile, config.BufferSize)
+	// Деление на предложения; word2vec и LineSentence ожидают предложение в строке
+	var segmenter *tokenizer.Segmenter
+	if config.OutputMode == "sentence" || format.NeedsSentences() {
+		segmenter = tokenizer.NewSegmenter()
+	}
+
+	// Журнал обработанных файлов; при возобновлении вывод сверяется с ним
+	var completed map[string]bool
+	var checkpoints writer.Checkpointer
+	if config.Journal.Enable {
+		j, err := openJournal(config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		completed = j.Completed()
+		every := time.Duration(config.Journal.Interval) * time.Second
+		checkpoints = journal.NewRecorder(j, config.Journal.Path, every, config.Journal.Resume)
+	}
+
+	// Первый SIGINT/SIGTERM останавливает обработку корректно, второй
+	// завершает процесс сразу; трекер ошибок останавливает её по политике
+	ctx, cancel := context.WithCancel(handleSignals())
+	defer cancel()
+	policy, err := failures.ParsePolicy(config.Errors.Policy)
+	if err != nil {
+		log.Fatalf("Invalid errors.policy setting: %v", err)
+	}
+	tracker, err := failures.NewTracker(policy, config.Errors.Retries, config.Errors.MaxErrors, config.Errors.Report, cancel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tracker.Close()
+
+	// Метрики для долгих запусков; nil — HTTP-сервер не запускается
+	var m *metrics.Metrics
+	if config.HTTP.Listen != "" {
+		m = metrics.New(config.WorkersCount)
+		addr, err := metrics.Serve(config.HTTP.Listen, m)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Metrics: http://%s/metrics, status: http://%s/status\n", addr, addr)
+	}
+
+	fileProcessor := processor.New(textCleaner, lem, config.Lemmatization.Enable, joiner, segmenter, tokenFilter, tracker, m)
+	resultWriter := writer.New(config.OutputFile, config.BufferSize, writerOptions, layout, checkpoints, tracker.WriteErrors(), reportSink)
+	if m != nil {
+		m.SetOutput(func() metrics.Output {
+			stats := resultWriter.GetStats()
+			return metrics.Output{Lines: stats.Lines, Bytes: stats.Bytes, Corrupted: stats.Corrupted}
+		})
+		m.SetErrors(func() map[string]int {
+			counts := make(map[string]int)
+			for class, n := range tracker.Counts() {
+				counts[string(class)] = n
+			}
+			return counts
+		})
+	}
 
 	// Обработка файлов
-	if err := processFiles(config, fileProcessor, resultWriter); err != nil {
+	if err := processFiles(ctx, config, fileProcessor, resultWriter, tokenFilter, tracker, completed, m); err != nil {
 		log.Fatal(err)
 	}
+	if stopErr := tracker.Stopped(); stopErr != nil {
+		m.SetState(metrics.StateFailed)
+		tokenFilter.Close()
+		tracker.Close()
+		fmt.Printf("\x1b[31mStopped on error (policy %s): %v\x1b[0m\n", policy, stopErr)
+		os.Exit(1)
+	}
+	if ctx.Err() != nil {
+		m.SetState(metrics.StateInterrupted)
+		tokenFilter.Close()
+		tracker.Close()
+		if config.Journal.Enable {
+			fmt.Printf("\x1b[33mInterrupted: output and journal are consistent, rerun with --resume to continue\x1b[0m\n")
+		} else {
+			fmt.Printf("\x1b[33mInterrupted\x1b[0m\n")
+		}
+		os.Exit(130)
+	}
+
+	if collector != nil {
+		if config.Journal.Resume {
+			fmt.Printf("\x1b[33mReport covers only documents written after resume\x1b[0m\n")
+		}
+		if err := writeReport(config, collector, resultWriter, tokenFilter, tracker); err != nil {
+			log.Fatal(err)
+		}
+	}
 
+	m.SetState(metrics.StateCompleted)
 	fmt.Printf("\n=== Processing completed in %v ===\n", time.Since(startTime))
 }
 
-func processFiles(config utils.Config, processor *processor.FileProcessor, resultWriter *writer.ResultWriter) error {
+// writeReport пишет отчёт о корпусе в JSON и/или HTML.
+func writeReport(config utils.Config, collector *report.Collector, resultWriter *writer.ResultWriter, tokenFilter *filter.TokenFilter, tracker *failures.Tracker) error {
+	stats := resultWriter.GetStats()
+	removed := tokenFilter.Stats()
+	r := collector.Report(config.OutputFile, []report.Count{
+		{Name: "stopwords", Count: removed.Stopwords},
+		{Name: "short_tokens", Count: removed.Short},
+		{Name: "long_tokens", Count: removed.Long},
+		{Name: "subsampled_tokens", Count: stats.Dropped},
+		{Name: "unlabeled_documents", Count: stats.Skipped},
+		{Name: "empty_documents", Count: stats.Empty},
+		{Name: "failed_files", Count: uint64(tracker.Total())},
+	})
+	if config.Report.JSON != "" {
+		if err := r.WriteJSON(config.Report.JSON); err != nil {
+			return err
+		}
+		fmt.Printf("Report: %s\n", config.Report.JSON)
+	}
+	if config.Report.HTML != "" {
+		if err := r.WriteHTML(config.Report.HTML); err != nil {
+			return err
+		}
+		fmt.Printf("Report: %s\n", config.Report.HTML)
+	}
+	return nil
+}
+
+// openJournal создаёт новый журнал или, при --resume, загружает журнал
+// прерванного запуска, проверяет настройки и готовит вывод к дозаписи.
+func openJournal(config utils.Config) (*journal.Journal, error) {
+	fingerprint, err := journal.Fingerprint(outputSettings(config))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fingerprint config: %v", err)
+	}
+	if !config.Journal.Resume {
+		return journal.New(config.OutputFile, fingerprint), nil
+	}
+
+	j, err := journal.Resume(config.Journal.Path, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if err := j.Restore(config.OutputFile); err != nil {
+		return nil, err
+	}
+	if config.Metadata != "" {
+		if err := j.RestoreMetadata(config.Metadata); err != nil {
+			return nil, err
+		}
+	}
+	fmt.Printf("Resuming: %d files done, %d lines (%d bytes) kept in %s\n",
+		j.Files, j.Lines, j.Offset, config.OutputFile)
+	return j, nil
+}
+
+// outputSettings оставляет настройки, от которых зависит содержимое
+// вывода: число рабочих, буферы и журнал на него не влияют.
+func outputSettings(config utils.Config) utils.Config {
+	config.WorkersCount = 0
+	config.BufferSize = 0
+	config.ReportEvery = 0
+	config.Journal = utils.Config{}.Journal
+	config.Errors = utils.Config{}.Errors
+	config.Ordering.ReorderBuffer = 0
+	config.Report = utils.Config{}.Report
+	config.HTTP = utils.Config{}.HTTP
+	config.Logger = utils.Config{}.Logger
+	config.Lemmatization.MystemPath = ""
+	return config
+}
+
+// handleSignals возвращает контекст, отменяемый первым SIGINT или
+// SIGTERM. Второй сигнал завершает процесс, не дожидаясь рабочих.
+func handleSignals() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		fmt.Printf("\n\x1b[33mReceived %v: finishing in-flight documents (send again to force exit)\x1b[0m\n", sig)
+		cancel()
+		<-signals
+		fmt.Printf("\n\x1b[31mForced exit\x1b[0m\n")
+		os.Exit(130)
+	}()
+	return ctx
+}
+
+func processFiles(ctx context.Context, config utils.Config, fileProcessor *processor.FileProcessor, resultWriter *writer.ResultWriter, tokenFilter *filter.TokenFilter, tracker *failures.Tracker, completed map[string]bool, m *metrics.Metrics) error {
 	// Исправленный поиск файлов с пробелами в именах
 	pattern := filepath.Join(config.InputDir, "*.gz")
 	matches, err := filepath.Glob(pattern)
@@ -176,15 +683,32 @@ func processFiles(config utils.Config, processor *processor.FileProcessor, resul
 		}
 	}
 
+	// Порядок обхода не зависит от файловой системы
+	sort.Strings(matches)
+
 	totalFiles := len(matches)
 	if totalFiles == 0 {
 		return fmt.Errorf("no .gz files found in directory %s", config.InputDir)
 	}
+	if len(completed) > 0 {
+		remaining := matches[:0]
+		for _, file := range matches {
+			if !completed[file] {
+				remaining = append(remaining, file)
+			}
+		}
+		fmt.Printf("Skipping %d files completed before\n", totalFiles-len(remaining))
+		matches = remaining
+		totalFiles = len(matches)
+	}
 	fmt.Printf("Found %d files to process\n", totalFiles)
 
 	// Каналы для работы
-	fileChan := make(chan string, config.WorkersCount*2)
-	textChan := make(chan string, config.WorkersCount*2)
+	fileChan := make(chan processor.Job, config.WorkersCount*2)
+	docChan := make(chan writer.Document, config.WorkersCount*2)
+	m.SetTotal(totalFiles)
+	m.AddQueue("files", func() int { return len(fileChan) }, cap(fileChan))
+	m.AddQueue("documents", func() int { return len(docChan) }, cap(docChan))
 	progressChan := make(chan int, config.WorkersCount)
 	done := make(chan struct{})
 
@@ -193,7 +717,7 @@ func processFiles(config utils.Config, processor *processor.FileProcessor, resul
 
 	// Запускаем писателя в отдельной горутине
 	go func() {
-		resultWriter.Write(textChan)
+		resultWriter.Write(ctx, docChan)
 		close(done)
 	}()
 
@@ -223,27 +747,40 @@ func processFiles(config utils.Config, processor *processor.FileProcessor, resul
 		wg.Add(1)
 		go func(id int) {
 			defer wg.Done()
-			processor.Work(id, fileChan, textChan, progressChan, resultWriter)
+			fileProcessor.Work(ctx, id, fileChan, docChan, progressChan, resultWriter)
 		}(i + 1)
 	}
 
-	// Отправляем файлы в канал для обработки
+	// Отправляем файлы в канал для обработки; после отмены новые не выдаются.
+	// В упорядоченном режиме Reserve держит рабочих в пределах буфера писателя
 	go func() {
-		for _, file := range matches {
-			fileChan <- file
+		defer close(fileChan)
+		for seq, file := range matches {
+			if !resultWriter.Reserve(ctx) {
+				return
+			}
+			select {
+			case fileChan <- processor.Job{Seq: seq, Path: file}:
+			case <-ctx.Done():
+				return
+			}
 		}
-		close(fileChan)
 	}()
 
 	wg.Wait()
-	close(textChan)
+	close(docChan)
 	close(progressChan)
 
 	// Ждем завершения писателя
 	<-done
 
 	// Вывод финальной статистики
-	printFinalStats(resultWriter)
+	manifestPath := writer.ManifestPath(config.OutputFile, writer.Compression(config.Sharding.Compression))
+	if len(config.Split.Ratios) > 0 {
+		// У каждой выборки свой манифест
+		manifestPath = strings.TrimSuffix(manifestPath, ".manifest.json") + ".{train,valid,test}.manifest.json"
+	}
+	printFinalStats(resultWriter, tokenFilter, tracker, manifestPath)
 
 	return nil
 }
@@ -275,7 +812,7 @@ func printProgress(processed, total uint64, writer *writer.ResultWriter) {
 		bar, percent*100, speed, stats.Lines)
 }
 
-func printFinalStats(writer *writer.ResultWriter) {
+func printFinalStats(writer *writer.ResultWriter, tokenFilter *filter.TokenFilter, tracker *failures.Tracker, manifestPath string) {
 	stats := writer.GetStats()
 	speed := float64(stats.Bytes) / 1024 / stats.Duration.Seconds()
 	mb := float64(stats.Bytes) / 1024 / 1024
@@ -284,6 +821,24 @@ func printFinalStats(writer *writer.ResultWriter) {
 	fmt.Printf("  Time:      %v\n", stats.Duration.Round(time.Second))
 	fmt.Printf("  Lines:     %d\n", stats.Lines)
 	fmt.Printf("  Corrupted: %d\n", stats.Corrupted) // Новая статистика
+	if stats.Skipped > 0 {
+		fmt.Printf("  Skipped:   %d unlabeled documents\n", stats.Skipped)
+	}
+	if stats.Dropped > 0 {
+		fmt.Printf("  Sampled:   %d frequent tokens dropped\n", stats.Dropped)
+	}
+	for _, split := range stats.Splits {
+		fmt.Printf("  %-10s %d documents, %d lines\n", strings.ToUpper(split.Name[:1])+split.Name[1:]+":", split.Documents, split.Lines)
+	}
+	if stats.Shards > 0 {
+		fmt.Printf("  Shards:    %d (manifest: %s)\n", stats.Shards, manifestPath)
+	}
+	if failed := tracker.Total(); failed > 0 {
+		fmt.Printf("  Failed:    %d files (%s)\n", failed, tracker.Summary())
+	}
+	removed := tokenFilter.Stats()
+	fmt.Printf("  Removed:   %d tokens (stopwords: %d, short: %d, long: %d)\n",
+		removed.Total(), removed.Stopwords, removed.Short, removed.Long)
 	fmt.Printf("  Data:      %.1f MB\n", mb)
 	fmt.Printf("  Speed:     %.1f KB/s\n", speed)
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/terratensor/text2glove/internal/vectors"
+)
+
+// runNeighbors отвечает на запросы ближайших соседей и аналогий.
+func runNeighbors(args []string) error {
+	flags := pflag.NewFlagSet("neighbors", pflag.ExitOnError)
+	vectorsFile := flags.String("vectors", "vectors.txt", "Vectors file")
+	format := flags.String("format", "auto", "Vectors format: auto|glove|word2vec|word2vec-bin|glove-bin|fasttext|t2g")
+	vocabFile := flags.String("vocab_file", "", "Vocabulary for binary GloVe vectors written by train")
+	k := flags.IntP("top", "k", 10, "Number of neighbours to show")
+	queries := flags.StringSlice("query", nil, "Queries to answer in batch mode, e.g. \"король - мужчина + женщина\"")
+	batch := flags.String("batch", "", "File with one query per line (\"-\" for stdin); answers are printed as TSV")
+	maxVocab := flags.Int("max_vocab", 0, "Search only the N most frequent words (0 = all)")
+	lowercase := flags.Bool("lowercase", true, "Lowercase queries")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	vectorsFormat, err := vectors.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	e, err := vectors.Load(*vectorsFile, vectorsFormat, *vocabFile)
+	if err != nil {
+		return err
+	}
+	e.Restrict(*maxVocab)
+	e.Normalize()
+	fmt.Fprintf(os.Stderr, "Loaded %d vectors of dimension %d in %v\n", e.Len(), e.Dim, time.Since(startTime).Round(time.Millisecond))
+
+	prepare := func(query string) string {
+		query = strings.TrimSpace(query)
+		if *lowercase {
+			query = strings.ToLower(query)
+		}
+		return query
+	}
+
+	// Пакетный режим: запросы из флагов, аргументов или файла, ответы в TSV
+	batchQueries := append(*queries, flags.Args()...)
+	if len(batchQueries) > 0 || *batch != "" {
+		out := bufio.NewWriter(os.Stdout)
+		defer out.Flush()
+		answer := func(query string) {
+			if query = prepare(query); query == "" || strings.HasPrefix(query, "#") {
+				return
+			}
+			neighbors, err := nearest(e, query, *k)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", query, err)
+				return
+			}
+			for rank, n := range neighbors {
+				fmt.Fprintf(out, "%s\t%d\t%s\t%.6f\n", query, rank+1, n.Word, n.Score)
+			}
+		}
+		for _, query := range batchQueries {
+			answer(query)
+		}
+		if *batch != "" {
+			return eachQuery(*batch, answer)
+		}
+		return nil
+	}
+
+	return repl(e, *k, prepare)
+}
+
+func nearest(e *vectors.Embeddings, query string, k int) ([]vectors.Neighbor, error) {
+	vector, exclude, err := e.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	return e.Nearest(vector, k, exclude), nil
+}
+
+// repl — интерактивный режим: запрос в строке, :k N меняет число соседей,
+// :q или Ctrl-D — выход.
+func repl(e *vectors.Embeddings, k int, prepare func(string) string) error {
+	fmt.Fprintf(os.Stderr, "Enter a word or an expression like \"король - мужчина + женщина\" (:k N to change top-k, :q to quit)\n")
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(os.Stderr, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(os.Stderr)
+			return scanner.Err()
+		}
+		query := prepare(scanner.Text())
+		switch {
+		case query == "":
+			continue
+		case query == ":q" || query == ":quit":
+			return nil
+		case strings.HasPrefix(query, ":k"):
+			n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(query, ":k")))
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "usage: :k N\n")
+				continue
+			}
+			k = n
+			continue
+		}
+
+		startTime := time.Now()
+		neighbors, err := nearest(e, query, k)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "\x1b[31m%v\x1b[0m\n", err)
+			continue
+		}
+		for rank, n := range neighbors {
+			fmt.Printf("%3d. %-30s %.4f\n", rank+1, n.Word, n.Score)
+		}
+		fmt.Fprintf(os.Stderr, "(%v)\n", time.Since(startTime).Round(time.Microsecond))
+	}
+}
+
+func eachQuery(path string, fn func(query string)) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open batch file: %v", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read batch file: %v", err)
+	}
+	return nil
+}
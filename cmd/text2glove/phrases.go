@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/terratensor/text2glove/internal/phrases"
+)
+
+// runPhrases объединяет устойчивые словосочетания в токены, как word2phrase.
+func runPhrases(args []string) error {
+	flags := pflag.NewFlagSet("phrases", pflag.ExitOnError)
+	input := flags.String("input", "", "Cleaned corpus (.gz supported); a positional argument is also accepted")
+	output := flags.StringP("output", "o", "phrases.txt", "Output corpus with phrases joined (\"-\" for stdout)")
+	minCount := flags.Uint64("min_count", 5, "Ignore words and bigrams that occur fewer than min_count times")
+	thresholds := flags.Float64Slice("threshold", []float64{100}, "Score threshold per pass (the last value is repeated for extra passes)")
+	passes := flags.Int("passes", 0, "Number of passes (default: one per threshold)")
+	scoring := flags.String("scoring", "default", "Bigram score: default (word2phrase) | npmi (threshold in [-1, 1])")
+	delimiter := flags.String("delimiter", "_", "Delimiter joining words of a phrase")
+	table := flags.String("table", "", "Write the phrase table (TSV) to this file")
+	maxVocab := flags.Int("max_vocab", phrases.DefaultMaxVocab, "Words and bigrams kept in memory before pruning rare ones")
+	tempDir := flags.String("temp_dir", "", "Directory for intermediate passes (default: system temp)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		*input = flags.Arg(0)
+	}
+	if *input == "" || *input == "-" {
+		return fmt.Errorf("phrases needs an input file: the corpus is read twice per pass")
+	}
+	scoringFunc, err := phrases.ParseScoring(*scoring)
+	if err != nil {
+		return err
+	}
+
+	perPass := *thresholds
+	if len(perPass) == 0 {
+		return fmt.Errorf("no thresholds given")
+	}
+	for len(perPass) < *passes {
+		perPass = append(perPass, perPass[len(perPass)-1])
+	}
+	if *passes > 0 {
+		perPass = perPass[:*passes]
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	startTime := time.Now()
+	fmt.Fprintf(os.Stderr, "=== Detecting phrases ===\n")
+	fmt.Fprintf(os.Stderr, "Scoring: %s, thresholds: %v, min count: %d\n", scoringFunc, perPass, *minCount)
+
+	found, stats, err := phrases.Run(*input, out, phrases.Options{
+		MinCount:   *minCount,
+		Thresholds: perPass,
+		Scoring:    scoringFunc,
+		Delimiter:  *delimiter,
+		MaxVocab:   *maxVocab,
+		TempDir:    *tempDir,
+	})
+	if err != nil {
+		return err
+	}
+	for i, s := range stats {
+		fmt.Fprintf(os.Stderr, "Pass %d: %d tokens, %d bigrams joined\n", i+1, s.Tokens, s.Joined)
+	}
+
+	if *table != "" {
+		file, err := os.Create(*table)
+		if err != nil {
+			return fmt.Errorf("failed to create phrase table: %v", err)
+		}
+		defer file.Close()
+		if err := phrases.WriteTable(file, found); err != nil {
+			return fmt.Errorf("failed to write phrase table: %v", err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Found %d phrases, wrote %s in %v\n", len(found), *output, time.Since(startTime).Round(time.Millisecond))
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/terratensor/text2glove/internal/glove"
+)
+
+// runShuffle перемешивает записи совместной встречаемости, как shuffle из GloVe.
+func runShuffle(args []string) error {
+	flags := pflag.NewFlagSet("shuffle", pflag.ExitOnError)
+	input := flags.String("input", "cooccurrence.bin", "Input file with CREC records (\"-\" for stdin)")
+	output := flags.StringP("output", "o", "cooccurrence.shuf.bin", "Output file (\"-\" for stdout)")
+	memory := flags.Float64("memory", glove.DefaultMemory, "Soft limit for memory consumption, in GB")
+	arraySize := flags.Int64("array_size", 0, "Records shuffled in memory at once (0 = derive from --memory)")
+	seed := flags.Int64("seed", 1, "Random seed; the same seed gives the same order")
+	tempDir := flags.String("temp_dir", "", "Directory for temporary files (default: system temp)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		*input = flags.Arg(0)
+	}
+
+	in := os.Stdin
+	if *input != "-" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return fmt.Errorf("failed to open input file: %v", err)
+		}
+		defer file.Close()
+		in = file
+	}
+	out := os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	startTime := time.Now()
+	fmt.Fprintf(os.Stderr, "=== Shuffling cooccurrences ===\n")
+	stats, err := glove.Shuffle(in, out, glove.ShuffleOptions{
+		Memory:    *memory,
+		ArraySize: *arraySize,
+		Seed:      *seed,
+		TempDir:   *tempDir,
+	})
+	if err != nil {
+		return err
+	}
+
+	if stats.Chunks > 0 {
+		fmt.Fprintf(os.Stderr, "Merged %d temporary files\n", stats.Chunks)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d shuffled records to %s in %v\n", stats.Records, *output, time.Since(startTime).Round(time.Millisecond))
+	return nil
+}
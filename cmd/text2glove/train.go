@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/terratensor/text2glove/internal/glove"
+	"github.com/terratensor/text2glove/internal/vocab"
+)
+
+// runTrain обучает векторы GloVe на перемешанных записях совместной встречаемости.
+func runTrain(args []string) error {
+	flags := pflag.NewFlagSet("train", pflag.ExitOnError)
+	input := flags.String("input", "cooccurrence.shuf.bin", "Shuffled cooccurrence file")
+	vocabFile := flags.String("vocab_file", "vocab.txt", "Vocabulary file used to build the cooccurrences")
+	saveFile := flags.String("save_file", "vectors", "Output file name without extension (.txt and/or .bin are appended)")
+	vectorSize := flags.Int("vector_size", 50, "Dimension of word vectors")
+	threads := flags.Int("threads", runtime.NumCPU(), "Number of training goroutines")
+	iterations := flags.Int("iter", 25, "Number of training iterations")
+	eta := flags.Float64("eta", 0.05, "Initial learning rate")
+	alpha := flags.Float64("alpha", 0.75, "Exponent of the weighting function")
+	xMax := flags.Float64("x_max", 100.0, "Cutoff of the weighting function")
+	gradClip := flags.Float64("grad_clip", 100.0, "Gradient component clipping value")
+	binaryMode := flags.Int("binary", 0, "Output format: 0 = text, 1 = binary, 2 = both")
+	model := flags.Int("model", glove.ModelWordContext, "Text output: 0 = word and context vectors with biases, 1 = word vectors, 2 = word + context vectors")
+	writeHeader := flags.Bool("write_header", false, "Write \"<vocab_size> <vector_size>\" as the first line of the text output")
+	seed := flags.Int64("seed", 1, "Random seed for initialization")
+	checkpoint := flags.String("checkpoint", "", "Checkpoint file (default: <save_file>.ckpt)")
+	checkpointEvery := flags.Int("checkpoint_every", 1, "Save a checkpoint every N iterations (0 = never)")
+	resume := flags.Bool("resume", false, "Continue training from the checkpoint if it exists")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *vectorSize <= 0 || *iterations < 0 {
+		return fmt.Errorf("vector_size must be positive and iter non-negative")
+	}
+	if *binaryMode < 0 || *binaryMode > 2 || *model < 0 || *model > 2 {
+		return fmt.Errorf("binary and model must be 0, 1 or 2")
+	}
+	if *checkpoint == "" {
+		*checkpoint = *saveFile + ".ckpt"
+	}
+
+	entries, err := vocab.Read(*vocabFile)
+	if err != nil {
+		return err
+	}
+	words := make([]string, len(entries))
+	for i, e := range entries {
+		words[i] = e.Word
+	}
+
+	startTime := time.Now()
+	fmt.Fprintf(os.Stderr, "=== Training GloVe ===\n")
+	fmt.Fprintf(os.Stderr, "Vocabulary: %d words, vector size: %d, threads: %d\n", len(words), *vectorSize, *threads)
+
+	opts := glove.TrainOptions{
+		Threads:         *threads,
+		Iterations:      *iterations,
+		Eta:             *eta,
+		Alpha:           *alpha,
+		XMax:            *xMax,
+		GradClip:        *gradClip,
+		Checkpoint:      *checkpoint,
+		CheckpointEvery: *checkpointEvery,
+	}
+	if *checkpointEvery <= 0 {
+		opts.Checkpoint = ""
+	}
+
+	var m *glove.Model
+	if *resume {
+		if _, err := os.Stat(*checkpoint); err == nil {
+			if m, err = glove.LoadCheckpoint(*checkpoint); err != nil {
+				return err
+			}
+			source, err := glove.NewTrainSource(*input, opts)
+			if err != nil {
+				return err
+			}
+			if err := m.CheckResume(len(words), *vectorSize, source); err != nil {
+				return fmt.Errorf("cannot resume from %s: %v", *checkpoint, err)
+			}
+			fmt.Fprintf(os.Stderr, "Resuming from %s after iteration %d\n", *checkpoint, m.Iteration)
+		}
+	}
+	if m == nil {
+		m = glove.NewModel(len(words), *vectorSize, *seed)
+	}
+
+	err = glove.Train(m, *input, opts, func(iter int, cost float64) {
+		fmt.Fprintf(os.Stderr, "%s, iter: %03d, cost: %f\n", time.Now().Format("01/02/06 - 15:04.05"), iter, cost)
+	})
+	if err != nil {
+		return err
+	}
+
+	if *binaryMode != 1 {
+		if err := writeVectors(*saveFile+".txt", func(f *os.File) error {
+			return m.WriteText(f, words, *model, *writeHeader)
+		}); err != nil {
+			return err
+		}
+	}
+	if *binaryMode != 0 {
+		if err := writeVectors(*saveFile+".bin", func(f *os.File) error {
+			return m.WriteBinary(f)
+		}); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Saved vectors to %s in %v\n", *saveFile, time.Since(startTime).Round(time.Millisecond))
+	return nil
+}
+
+func writeVectors(path string, write func(f *os.File) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create vectors file: %v", err)
+	}
+	defer file.Close()
+	if err := write(file); err != nil {
+		return fmt.Errorf("failed to write vectors: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/terratensor/text2glove/internal/vocab"
+)
+
+// runVocab считает словарь корпуса, как vocab_count из GloVe.
+func runVocab(args []string) error {
+	flags := pflag.NewFlagSet("vocab", pflag.ExitOnError)
+	inputs := flags.StringSlice("input", nil, "Corpus files (\"-\" for stdin, .gz supported); positional arguments are also accepted")
+	output := flags.StringP("output", "o", "vocab.txt", "Output vocabulary file (\"-\" for stdout)")
+	minCount := flags.Uint64("min_count", 1, "Lower limit such that words which occur fewer than min_count times are discarded")
+	maxVocab := flags.Int("max_vocab", 0, "Upper bound on vocabulary size (0 = no limit)")
+	workers := flags.Int("workers", runtime.NumCPU(), "Number of counting workers")
+	memoryWords := flags.Int("memory_words", vocab.DefaultMemoryWords, "Distinct words kept in memory before spilling counts to disk")
+	tempDir := flags.String("temp_dir", "", "Directory for spill files (default: system temp)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	paths := append(*inputs, flags.Args()...)
+	if len(paths) == 0 {
+		return fmt.Errorf("no corpus files given: use --input or positional arguments")
+	}
+
+	startTime := time.Now()
+	fmt.Fprintf(os.Stderr, "=== Counting vocabulary ===\n")
+	fmt.Fprintf(os.Stderr, "Corpus: %v\n", paths)
+
+	entries, stats, err := vocab.Count(paths, vocab.Options{
+		Workers:     *workers,
+		MinCount:    *minCount,
+		MaxVocab:    *maxVocab,
+		MemoryWords: *memoryWords,
+		TempDir:     *tempDir,
+	})
+	if err != nil {
+		return err
+	}
+
+	out := os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create vocabulary file: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	if err := vocab.Write(out, entries); err != nil {
+		return fmt.Errorf("failed to write vocabulary: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Processed %d tokens, %d unique words\n", stats.Tokens, stats.Unique)
+	if stats.Unknown > 0 {
+		fmt.Fprintf(os.Stderr, "\x1b[33mSkipped %d reserved <unk> tokens\x1b[0m\n", stats.Unknown)
+	}
+	if stats.Spills > 0 {
+		fmt.Fprintf(os.Stderr, "Spilled counts to disk %d times\n", stats.Spills)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %d words to %s in %v\n", len(entries), *output, time.Since(startTime).Round(time.Millisecond))
+	return nil
+}
@@ -3,6 +3,7 @@ module github.com/terratensor/text2glove
 go 1.24.1
 
 require (
+	github.com/klauspost/compress v1.18.0
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.16.0
 	golang.org/x/text v0.9.0
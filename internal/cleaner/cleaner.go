@@ -19,17 +19,22 @@ const (
 )
 
 type CleanOptions struct {
-	KeepNumbers      bool // сохранять арабские цифры
-	KeepRomanNumbers bool // сохранять римские цифры
+	Numbers      NumberStrategy // обработка арабских чисел
+	RomanNumbers NumberStrategy // обработка римских чисел
+
+	// NormalizeSlavonic включает нормализацию церковнославянского текста:
+	// раскрытие титл, удаление надстрочных знаков, сведение вариантных букв.
+	NormalizeSlavonic bool
+	// SlavonicAbbreviations дополняет встроенный словарь сокращений под титлом.
+	SlavonicAbbreviations map[string]string
 }
 
 type TextCleaner struct {
 	re           *regexp.Regexp
 	urlRe        *regexp.Regexp
 	emailRe      *regexp.Regexp
-	romanNumRe   *regexp.Regexp
-	numbersRe    *regexp.Regexp
 	whitespaceRe *regexp.Regexp
+	slavonic     *SlavonicNormalizer
 	mode         CleanMode
 	options      CleanOptions
 }
@@ -43,15 +48,18 @@ func New(mode CleanMode, options CleanOptions) *TextCleaner {
 		options:      options,
 	}
 
-	// Инициализация regex для чисел только если нужно
-	if !options.KeepRomanNumbers {
-		cleaner.romanNumRe = regexp.MustCompile(`\b[IVXLCDMivxlcdm]+\b`)
+	if cleaner.options.Numbers == "" {
+		cleaner.options.Numbers = NumbersDrop
 	}
-	if !options.KeepNumbers {
-		cleaner.numbersRe = regexp.MustCompile(`\b\d+\b`)
+	if cleaner.options.RomanNumbers == "" {
+		cleaner.options.RomanNumbers = NumbersDrop
 	}
 
-	cleaner.re = createCleanupRegexp(mode, options)
+	if options.NormalizeSlavonic {
+		cleaner.slavonic = NewSlavonicNormalizer(options.SlavonicAbbreviations)
+	}
+
+	cleaner.re = createCleanupRegexp(mode)
 	return cleaner
 }
 
@@ -69,23 +77,42 @@ func (c *TextCleaner) Clean(text string) string {
 	text = c.replaceControlChars(text)
 	text = c.replaceUnicodeReplacementChars(text)
 
+	// 4a. Удаление мягких переносов (иначе шаг 7 разорвал бы ими слова);
+	// в режиме, сохраняющем дефисы и апострофы внутри слов, их варианты
+	// приводятся к - и '
+	text = strings.ReplaceAll(text, softHyphen, "")
+	if c.mode == ModeUnicodeLettersAndNumbers {
+		text = punctuationVariants.Replace(text)
+	}
+
 	// 5. Удаление URL и email
 	text = c.urlRe.ReplaceAllString(text, " ")
 	text = c.emailRe.ReplaceAllString(text, " ")
 
+	// 5a. Римские числа (распознаются по заглавным буквам, до нижнего регистра)
+	if strings.ContainsRune(text, romanMarker) {
+		text = strings.ReplaceAll(text, string(romanMarker), " ")
+	}
+	text = c.replaceRomanNumbers(text)
+
 	// 6. Приведение к нижнему регистру
 	text = strings.ToLower(text)
 
-	// 7. Удаление чисел (если нужно)
-	if !c.options.KeepRomanNumbers && c.romanNumRe != nil {
-		text = c.romanNumRe.ReplaceAllString(text, " ")
+	// 6a. Церковнославянская нормализация (до удаления символов, иначе
+	// надстрочные знаки разрывают слова)
+	if c.slavonic != nil {
+		text = c.slavonic.Normalize(text)
 	}
-	// if !c.options.KeepNumbers && c.numbersRe != nil {
-	// 	text = c.numbersRe.ReplaceAllString(text, " ")
-	// }
 
-	// 8. Удаление нежелательных символов по режиму
+	// 7. Удаление нежелательных символов по режиму
 	text = c.re.ReplaceAllString(text, " ")
+	if c.mode == ModeUnicodeLettersAndNumbers {
+		text = keepIntraWordPunctuation(text)
+	}
+
+	// 8. Числа: удаление или замена токенами (после удаления символов,
+	// чтобы сохранить угловые скобки заменителей)
+	text = c.replaceNumbers(text)
 
 	// 9. Нормализация пробелов
 	text = c.whitespaceRe.ReplaceAllString(text, " ")
@@ -139,37 +166,65 @@ func (c *TextCleaner) replaceControlChars(text string) string {
 	return buf.String()
 }
 
+// softHyphen — мягкий перенос: «пере\u00ADнос» — одно слово
+const softHyphen = "\u00AD"
+
+// punctuationVariants сводит типографские дефисы и апострофы к ASCII-символам
+var punctuationVariants = strings.NewReplacer(
+	"\u2010", "-", // дефис
+	"\u2011", "-", // неразрывный дефис
+	"\u2012", "-", // цифровое тире
+	"\u2013", "-", // короткое тире
+	"\u2019", "'", // правая одинарная кавычка
+	"\u2018", "'", // левая одинарная кавычка
+	"\u02BC", "'", // буква-апостроф
+	"\u0060", "'", // гравис
+	"\u00B4", "'", // акут
+	"\u2032", "'", // штрих
+)
+
+// keepIntraWordPunctuation оставляет дефисы и апострофы только между буквами
+// («северо-запад», «o'neil»), остальные заменяет на пробелы
+func keepIntraWordPunctuation(text string) string {
+	if !strings.ContainsAny(text, "-'") {
+		return text
+	}
+
+	runes := []rune(text)
+	for i, r := range runes {
+		if r != '-' && r != '\'' {
+			continue
+		}
+		if i == 0 || i == len(runes)-1 || !unicode.IsLetter(runes[i-1]) || !unicode.IsLetter(runes[i+1]) {
+			runes[i] = ' '
+		}
+	}
+	return string(runes)
+}
+
 // replaceUnicodeReplacementChars заменяет символы замены Unicode (�) на пробелы
 func (c *TextCleaner) replaceUnicodeReplacementChars(text string) string {
 	return strings.ReplaceAll(text, "\uFFFD", " ")
 }
 
-func createCleanupRegexp(mode CleanMode, options CleanOptions) *regexp.Regexp {
+func createCleanupRegexp(mode CleanMode) *regexp.Regexp {
 	var pattern string
 
+	// Цифры и метка римских чисел сохраняются во всех режимах:
+	// их обработку по стратегии выполняет replaceNumbers
+	const numbers = `\p{N}\x{E000}`
+
 	switch mode {
 	case ModeModern:
 		// Современные языки: русский, английский, основные европейские
-		if options.KeepNumbers {
-			pattern = `[^\p{L}\p{N}\sа-яёa-zà-ÿğüşıöç.,!?;:'"-]`
-		} else {
-			pattern = `[^\p{L}\sа-яёa-zà-ÿğüşıöç.,!?;:'"-]`
-		}
+		pattern = `[^\p{L}` + numbers + `\sа-яёa-zà-ÿğüşıöç.,!?;:'"-]`
 
 	case ModeOldSlavonic:
 		oldSlavonicChars := "ѣѢѵѴіІѳѲѫѪѭѬѧѦѩѨѯѮѱѰѡѠѿѾҌҍꙋꙊꙗꙖꙙꙘꙜꙛꙝꙞꙟꙠꙡꙢꙣꙤꙥꙦꙧꙨꙩꙪꙫꙬꙭꙮѻѺѹѸѷѶѵѴѳѲѱѰѯѮѭѬѫѪѩѨѧѦѥѤѣѢѣѢѡѠџЏѾѽѼѻѺѹѸ"
-		if options.KeepNumbers {
-			pattern = `[^\p{L}\p{N}\s` + oldSlavonicChars + `.,!?;:'"-]`
-		} else {
-			pattern = `[^\p{L}\s` + oldSlavonicChars + `.,!?;:'"-]`
-		}
+		pattern = `[^\p{L}` + numbers + `\s` + oldSlavonicChars + `.,!?;:'"-]`
 
 	case ModeAll:
-		if options.KeepNumbers {
-			pattern = `[^\p{L}\p{N}\s.,!?;:'"-]`
-		} else {
-			pattern = `[^\p{L}\s.,!?;:'"-]`
-		}
+		pattern = `[^\p{L}` + numbers + `\s.,!?;:'"-]`
 
 	case ModeUnicodeLettersAndNumbers:
 		// Все буквы Unicode и цифры и без CJK иероглифов
@@ -178,13 +233,9 @@ func createCleanupRegexp(mode CleanMode, options CleanOptions) *regexp.Regexp {
 		// \p{Hangul} - корейский алфавит
 		// \p{Hiragana} и \p{Katakana} - японские слоговые азбуки
 		// \p{Bopomofo} - китайская фонетическая азбука
-		// .,!?;:'"- - знаки препинания
-		// punctuation := `.,!?;:'"-`
-		if options.KeepNumbers {
-			pattern = `[\p{Han}\p{Hangul}\p{Hiragana}\p{Katakana}\p{Bopomofo}]|[^\p{L}\p{N}\s]`
-		} else {
-			pattern = `[\p{Han}\p{Hangul}\p{Hiragana}\p{Katakana}\p{Bopomofo}]|[^\p{L}\s]`
-		}
+		// Дефис и апостроф пропускаются здесь и затем сохраняются только
+		// внутри слов (см. keepIntraWordPunctuation)
+		pattern = `[\p{Han}\p{Hangul}\p{Hiragana}\p{Katakana}\p{Bopomofo}]|[^\p{L}` + numbers + `\s'-]`
 	}
 
 	re, err := regexp.Compile(pattern)
@@ -0,0 +1,338 @@
+package cleaner
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NumberStrategy определяет, что делать с числами в тексте.
+type NumberStrategy string
+
+const (
+	NumbersKeep        NumberStrategy = "keep"        // оставить как есть
+	NumbersDrop        NumberStrategy = "drop"        // удалить
+	NumbersPlaceholder NumberStrategy = "placeholder" // заменить на <NUM>
+	NumbersShape       NumberStrategy = "shape"       // заменить на <NUMn>, n — число цифр
+	NumbersBucket      NumberStrategy = "bucket"      // заменить на токен диапазона: <NUM_10_99>, <YEAR>, …
+)
+
+// ParseNumberStrategy проверяет имя стратегии из конфигурации.
+func ParseNumberStrategy(s string) (NumberStrategy, error) {
+	switch strategy := NumberStrategy(s); strategy {
+	case NumbersKeep, NumbersDrop, NumbersPlaceholder, NumbersShape, NumbersBucket:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown number strategy %q: expected keep|drop|placeholder|shape|bucket", s)
+}
+
+// replacesNumbers сообщает, заменяет ли стратегия числа токенами.
+func (s NumberStrategy) replacesNumbers() bool {
+	return s == NumbersPlaceholder || s == NumbersShape || s == NumbersBucket
+}
+
+// romanMarker помечает значение римского числа, переведённое в арабские цифры
+// до приведения к нижнему регистру, чтобы обработать его по стратегии
+// для римских чисел после удаления лишних символов.
+const romanMarker = '\uE000'
+
+var (
+	romanCandidateRe = regexp.MustCompile(`[IVXLCDM]+`)
+	romanValidRe     = regexp.MustCompile(`^M{0,3}(CM|CD|D?C{0,3})(XC|XL|L?X{0,3})(IX|IV|V?I{0,3})$`)
+)
+
+var romanValues = map[byte]int{'I': 1, 'V': 5, 'X': 10, 'L': 50, 'C': 100, 'D': 500, 'M': 1000}
+
+// replaceRomanNumbers находит римские числа в тексте с исходным регистром.
+// Учитываются только записанные заглавными буквами и корректные по правилам
+// записи числа, поэтому слова «mix», «civil», «did» не затрагиваются.
+// Заглавные «MIX», «CD», «DC» — чаще слова и аббревиатуры, поэтому
+// несколько букв считаются числом только в окружении числа (romanContext):
+// «XIV век», «глава IV», «при Людовике XIV», «XIX–XX вв.». Одиночная буква
+// считается числом и без него, если рядом нет латиницы («Глава I», но не
+// «then I went»).
+func (c *TextCleaner) replaceRomanNumbers(text string) string {
+	strategy := c.options.RomanNumbers
+	if strategy == NumbersKeep || !strings.ContainsAny(text, "IVXLCDM") {
+		return text
+	}
+
+	matches := romanCandidateRe.FindAllStringIndex(text, -1)
+	if matches == nil {
+		return text
+	}
+
+	var buf strings.Builder
+	buf.Grow(len(text))
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		numeral := text[start:end]
+		if !isStandalone(text, start, end) || !romanValidRe.MatchString(numeral) {
+			continue
+		}
+		if len(numeral) == 1 && nearLatin(text, start, end) && !romanContext(text, start, end) {
+			continue
+		}
+		if len(numeral) > 1 && !romanContext(text, start, end) {
+			continue
+		}
+
+		buf.WriteString(text[last:start])
+		if strategy == NumbersDrop {
+			buf.WriteRune(' ')
+		} else {
+			buf.WriteRune(romanMarker)
+			buf.WriteString(strconv.Itoa(romanValue(numeral)))
+		}
+		last = end
+	}
+	buf.WriteString(text[last:])
+	return buf.String()
+}
+
+// replaceNumbers применяет стратегии к последовательностям цифр. Вызывается
+// после удаления лишних символов, поэтому токены-заменители сохраняются.
+// Цифры внутри слов («covid19») заменяются только стратегией drop.
+func (c *TextCleaner) replaceNumbers(text string) string {
+	if c.options.Numbers == NumbersKeep && !strings.ContainsRune(text, romanMarker) {
+		return text
+	}
+
+	runes := []rune(text)
+	var buf strings.Builder
+	buf.Grow(len(text))
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if r != romanMarker && !unicode.IsNumber(r) {
+			buf.WriteRune(r)
+			i++
+			continue
+		}
+
+		roman := r == romanMarker
+		start := i
+		if roman {
+			start++
+		}
+		end := start
+		for end < len(runes) && unicode.IsNumber(runes[end]) {
+			end++
+		}
+		digits := string(runes[start:end])
+		standalone := (i == 0 || !unicode.IsLetter(runes[i-1])) && (end == len(runes) || !unicode.IsLetter(runes[end]))
+
+		switch {
+		case roman:
+			buf.WriteString(" " + renderNumber(c.options.RomanNumbers, digits) + " ")
+		case c.options.Numbers == NumbersDrop:
+			buf.WriteRune(' ')
+		case c.options.Numbers.replacesNumbers() && standalone:
+			buf.WriteString(" " + renderNumber(c.options.Numbers, digits) + " ")
+		default:
+			buf.WriteString(digits)
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+// renderNumber возвращает токен-заменитель числа для стратегии.
+func renderNumber(strategy NumberStrategy, digits string) string {
+	switch strategy {
+	case NumbersShape:
+		return fmt.Sprintf("<NUM%d>", utf8.RuneCountInString(digits))
+	case NumbersBucket:
+		return numberBucket(digits)
+	}
+	return "<NUM>"
+}
+
+// numberBucket относит число к диапазону; четырёхзначные числа 1000–2099
+// считаются годами.
+func numberBucket(digits string) string {
+	value, err := strconv.ParseUint(digits, 10, 64)
+	switch {
+	case err != nil && len(digits) > 4:
+		return "<NUM_BIG>"
+	case err != nil:
+		return "<NUM>"
+	case value < 10:
+		return "<NUM_0_9>"
+	case value < 100:
+		return "<NUM_10_99>"
+	case value < 1000:
+		return "<NUM_100_999>"
+	case value < 2100 && len(digits) == 4:
+		return "<YEAR>"
+	case value < 10000:
+		return "<NUM_1000_9999>"
+	}
+	return "<NUM_BIG>"
+}
+
+func romanValue(numeral string) int {
+	total := 0
+	for i := 0; i < len(numeral); i++ {
+		v := romanValues[numeral[i]]
+		if i+1 < len(numeral) && v < romanValues[numeral[i+1]] {
+			total -= v
+		} else {
+			total += v
+		}
+	}
+	return total
+}
+
+// isStandalone проверяет, что text[start:end] не является частью слова.
+func isStandalone(text string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }
+	return (start == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after))
+}
+
+// nearLatin сообщает, является ли ближайшая буква слева или справа латинской.
+func nearLatin(text string, start, end int) bool {
+	for before := text[:start]; before != ""; {
+		r, size := utf8.DecodeLastRuneInString(before)
+		if unicode.IsLetter(r) {
+			return unicode.Is(unicode.Latin, r)
+		}
+		before = before[:len(before)-size]
+	}
+	for _, r := range text[end:] {
+		if unicode.IsLetter(r) {
+			return unicode.Is(unicode.Latin, r)
+		}
+	}
+	return false
+}
+
+// Начала слов, рядом с которыми стоят римские числа: после числа
+// («XIV век», «XIX вв.», «XX съезд») и перед ним («глава IV», «chapter XI»).
+var (
+	romanFollowers = []string{"век", "вв", "столет", "тысячелет", "съезд", "созыв", "centur"}
+	romanLeaders   = []string{"глав", "том", "част", "книг", "раздел", "chapter", "part", "volume", "vol", "book"}
+)
+
+// romanContext сообщает, похоже ли окружение text[start:end] на окружение
+// римского числа: соседнее слово из romanFollowers или romanLeaders,
+// сокращение «в.» после числа, имя перед ним («при Людовике XIV»;
+// слово с заглавной не в начале предложения), диапазон через тире с другим
+// римским числом («XIX–XX») или окончание порядкового через дефис («XIX-го»).
+func romanContext(text string, start, end int) bool {
+	prev, prevGap, sentenceStart := wordBefore(text, start)
+	next, nextGap, rest := wordAfter(text, end)
+	lowerPrev, lowerNext := strings.ToLower(prev), strings.ToLower(next)
+
+	for _, prefix := range romanFollowers {
+		if strings.HasPrefix(lowerNext, prefix) {
+			return true
+		}
+	}
+	for _, prefix := range romanLeaders {
+		if strings.HasPrefix(lowerPrev, prefix) {
+			return true
+		}
+	}
+	if lowerNext == "в" && strings.HasPrefix(rest, ".") {
+		return true
+	}
+	if isDash(prevGap) && isRoman(prev) || isDash(nextGap) && isRoman(next) {
+		return true
+	}
+	if nextGap == "-" && utf8.RuneCountInString(next) <= 3 && isLowerCyrillic(next) {
+		return true
+	}
+	return !sentenceStart && isCapitalized(prev)
+}
+
+// wordBefore возвращает слово перед start, разделитель между ними и
+// признак того, что слово начинает предложение.
+func wordBefore(text string, start int) (word, gap string, sentenceStart bool) {
+	i := start
+	for i > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:i])
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			break
+		}
+		i -= size
+	}
+	gap = strings.TrimSpace(text[i:start])
+	j := i
+	for j > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:j])
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			break
+		}
+		j -= size
+	}
+	before := strings.TrimRightFunc(text[:j], unicode.IsSpace)
+	sentenceStart = before == "" || strings.ContainsAny(before[len(before)-1:], ".!?")
+	return text[j:i], gap, sentenceStart
+}
+
+// wordAfter возвращает слово после end, разделитель перед ним и текст
+// после слова.
+func wordAfter(text string, end int) (word, gap, rest string) {
+	i := end
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			break
+		}
+		i += size
+	}
+	gap = strings.TrimSpace(text[end:i])
+	j := i
+	for j < len(text) {
+		r, size := utf8.DecodeRuneInString(text[j:])
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			break
+		}
+		j += size
+	}
+	return text[i:j], gap, text[j:]
+}
+
+func isDash(s string) bool {
+	return s == "-" || s == "–" || s == "—"
+}
+
+// isRoman сообщает, записано ли слово заглавными римскими цифрами.
+func isRoman(word string) bool {
+	return word != "" && strings.Trim(word, "IVXLCDM") == "" && romanValidRe.MatchString(word)
+}
+
+func isLowerCyrillic(word string) bool {
+	for _, r := range word {
+		if !unicode.Is(unicode.Cyrillic, r) || !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return word != ""
+}
+
+// isCapitalized сообщает, что слово из букв начинается с заглавной, а
+// остальные строчные («Людовик», но не «MIX» и не «купил»).
+func isCapitalized(word string) bool {
+	first, size := utf8.DecodeRuneInString(word)
+	if size == len(word) || !unicode.IsUpper(first) {
+		return false
+	}
+	for _, r := range word[size:] {
+		if !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPlaceholder сообщает, является ли токен заменителем вида <NUM>.
+func IsPlaceholder(token string) bool {
+	return len(token) > 2 && token[0] == '<' && token[len(token)-1] == '>'
+}
@@ -0,0 +1,44 @@
+package cleaner
+
+import "testing"
+
+// Римские числа заменяются токеном только в окружении числа; заглавные
+// слова и аббревиатуры остаются словами.
+func TestRomanNumbers(t *testing.T) {
+	c := New(ModeModern, CleanOptions{Numbers: NumbersKeep, RomanNumbers: NumbersBucket})
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"XIV век", "<NUM_10_99> век"},
+		{"в XIX веке", "в <NUM_10_99> веке"},
+		{"к концу XIX в.", "к концу <NUM_10_99> в."},
+		{"глава IV", "глава <NUM_0_9>"},
+		{"Том II, часть III", "том <NUM_0_9> , часть <NUM_0_9>"},
+		{"при Людовике XIV", "при людовике <NUM_10_99>"},
+		{"under Henry VIII", "under henry <NUM_0_9>"},
+		{"Chapter XI", "chapter <NUM_10_99>"},
+		{"XIX–XX вв.", "<NUM_10_99> <NUM_10_99> вв."},
+		{"XIX-го", "<NUM_10_99> -го"},
+		{"Глава I", "глава <NUM_0_9>"},
+		{"Пётр I", "пётр <NUM_0_9>"},
+		{"then I went", "then i went"},
+		{"MIX DIV CD", "mix div cd"},
+		{"THE MIX", "the mix"},
+		{"купил CD диск", "купил cd диск"},
+		{"CD с музыкой", "cd с музыкой"},
+		{"Купил CD.", "купил cd."},
+		{"резюме CV и MC Hammer", "резюме cv и mc hammer"},
+		{"DC Comics", "dc comics"},
+		{"LI ion", "li ion"},
+		{"mix civil did", "mix civil did"},
+		{"IIII век", "iiii век"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := c.Clean(tt.text); got != tt.want {
+				t.Fatalf("Clean(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
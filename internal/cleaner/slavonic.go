@@ -0,0 +1,260 @@
+package cleaner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	titlo     = '\u0483' // церковнославянское титло
+	pokrytie  = '\u0487' // покрытие (титло над надстрочной буквой)
+	vzmet     = '\uA66F' // взмет
+	breve     = '\u0306' // кратка: й, ў
+	diaeresis = '\u0308' // трема: ё, ї
+)
+
+// builtinAbbreviations содержит распространённые сокращения под титлом.
+// Ключи записаны в нормализованном виде (см. abbreviationKey): без ударений
+// и придыханий, с вариантными буквами, сведёнными к основным, и с титлом
+// U+0483 на месте любого знака сокращения.
+var builtinAbbreviations = map[string]string{
+	"бг҃ъ": "богъ", "бг҃а": "бога", "бг҃у": "богу", "бг҃омъ": "богомъ",
+	"бж҃е": "боже", "бз҃ѣ": "бозѣ", "бж҃ій": "божій", "бж҃ія": "божія", "бж҃іе": "божіе",
+	"гд҃ь": "господь", "гд҃и": "господи",
+	"гдс҃ь": "господь", "гдс҃и": "господи", "гдс҃а": "господа", "гдс҃у": "господу",
+	"гдс҃омъ": "господомъ", "гдс҃ѣ": "господѣ",
+	"іи҃съ": "іисусъ", "іи҃са": "іисуса", "іи҃су": "іисусу", "іс҃ъ": "іисусъ",
+	"хс҃ъ": "христосъ", "хр҃тосъ": "христосъ", "хр҃та": "христа", "хр҃ту": "христу", "хр҃тѣ": "христѣ",
+	"дх҃ъ": "духъ", "дх҃а": "духа", "дх҃у": "духу", "дх҃омъ": "духомъ",
+	"ст҃ый": "святый", "ст҃аго": "святаго", "ст҃ая": "святая", "ст҃ое": "святое",
+	"ст҃ыхъ": "святыхъ", "ст҃ѣй": "святѣй", "ст҃ымъ": "святымъ",
+	"бц҃а": "богородица", "бц҃ы": "богородицы", "бц҃е": "богородице",
+	"мт҃и": "мати", "мт҃рь": "матерь", "мт҃ре": "матере",
+	"оц҃ъ": "отецъ", "оц҃а": "отца", "оч҃е": "отче", "оц҃у": "отцу",
+	"сн҃ъ": "сынъ", "сн҃а": "сына", "сн҃у": "сыну", "сн҃е": "сыне",
+	"цр҃ь": "царь", "цр҃я": "царя", "цр҃ю": "царю", "цр҃ство": "царство", "цр҃ствіе": "царствіе",
+	"цр҃ковь": "церковь", "цр҃кви": "церкви",
+	"агг҃лъ": "ангелъ", "агг҃ла": "ангела", "агг҃ли": "ангели", "агг҃льскій": "ангельскій",
+	"бл҃гъ": "благъ", "бл҃го": "благо", "бл҃годать": "благодать", "бл҃гословенъ": "благословенъ",
+	"чл҃вѣкъ": "человѣкъ", "чл҃вѣка": "человѣка", "чл҃вѣку": "человѣку", "чл҃вѣче": "человѣче",
+	"нб҃о": "небо", "нб҃са": "небеса", "нб҃сѣхъ": "небесѣхъ", "нбс҃ный": "небесный",
+	"млт҃ва": "молитва", "млт҃вы": "молитвы", "млс҃рдіе": "милосердіе",
+	"дв҃а": "дѣва", "дв҃ы": "дѣвы", "дв҃ѣ": "дѣвѣ",
+	"ап҃лъ": "апостолъ", "ап҃ла": "апостола", "ап҃ли": "апостоли",
+	"про҃ркъ": "пророкъ", "про҃рка": "пророка",
+	"сп҃съ": "спасъ", "сп҃сеніе": "спасеніе", "сп҃си": "спаси",
+}
+
+// slavonicFolds сводит вариантные начертания букв к основным.
+var slavonicFolds = map[rune]string{
+	'ѻ': "о", 'Ѻ': "о", // о широкое
+	'ѡ': "о", 'Ѡ': "о", // омега
+	'ꙍ': "о", 'Ꙍ': "о", // омега широкая
+	'ѽ': "о", 'Ѽ': "о", // омега с великим апострофом
+	'ѿ': "от", 'Ѿ': "от", // от
+	'ꙋ': "у", 'Ꙋ': "у", // ук монограф
+	'ѹ': "у", 'Ѹ': "у", // ук диграф
+	'ѫ': "у", 'Ѫ': "у", // юс большой
+	'ѭ': "ю", 'Ѭ': "ю", // юс большой йотированный
+	'ꙗ': "я", 'Ꙗ': "я", // а йотированное
+	'ѧ': "я", 'Ѧ': "я", // юс малый
+	'ѩ': "я", 'Ѩ': "я", // юс малый йотированный
+	'ꙁ': "з", 'Ꙁ': "з", // земля
+	'ꙃ': "з", 'Ꙃ': "з", // дзело
+	'ꙇ': "і", 'Ꙇ': "і", // иота
+}
+
+// sharedFolds — вариантные буквы, которые пишутся и в современных языках
+// (є, ї — украинский, ѕ — македонский). Они сводятся к основным только в
+// словах с церковнославянскими знаками или буквами (см. isSlavonicWord),
+// иначе «єдність» или «їжак» исказились бы.
+var sharedFolds = map[rune]string{
+	'є': "е", 'Є': "е", // есть широкое
+	'ѕ': "з", 'Ѕ': "з", // зело
+	'ї': "і", 'Ї': "і", // і с двумя точками
+}
+
+// slavonicLetters — буквы, которых нет в современных алфавитах.
+const slavonicLetters = "ѻѺѡѠꙍꙌѽѼѿѾꙋꙊѹѸѫѪѭѬꙗꙖѧѦѩѨꙁꙀꙃꙂꙇꙆѣѢѳѲѵѴѯѮѱѰ"
+
+// isSlavonicWord сообщает, есть ли в слове (в NFD) церковнославянские
+// надстрочные знаки или буквы. Острое ударение не учитывается: им
+// отмечают ударение и в современных текстах.
+func isSlavonicWord(word string) bool {
+	for _, r := range word {
+		switch {
+		case r == '\u0300' || r == '\u0311': // вария, камора
+			return true
+		case r >= '\u0483' && r <= '\u0489': // титло, придыхания, покрытие
+			return true
+		case r >= '\uA66F' && r <= '\uA67F': // взмет, паерок, кавыка
+			return true
+		case isCyrillicCombiningLetter(r), strings.ContainsRune(slavonicLetters, r):
+			return true
+		}
+	}
+	return false
+}
+
+// SlavonicNormalizer приводит церковнославянский текст к виду, пригодному
+// для обучения: раскрывает сокращения под титлом, убирает надстрочные знаки
+// и сводит вариантные начертания букв к основным.
+type SlavonicNormalizer struct {
+	abbreviations map[string]string
+}
+
+// NewSlavonicNormalizer создаёт нормализатор со встроенным словарём сокращений,
+// дополненным (или переопределённым) записями из extra.
+func NewSlavonicNormalizer(extra map[string]string) *SlavonicNormalizer {
+	abbreviations := make(map[string]string, len(builtinAbbreviations)+len(extra))
+	for k, v := range builtinAbbreviations {
+		abbreviations[k] = v
+	}
+	for k, v := range extra {
+		abbreviations[abbreviationKey(k)] = strings.ToLower(v)
+	}
+	return &SlavonicNormalizer{abbreviations: abbreviations}
+}
+
+// LoadAbbreviations читает словарь сокращений из файла.
+// Формат: по одной записи на строку, «сокращение раскрытие»;
+// пустые строки и строки, начинающиеся с #, пропускаются.
+func LoadAbbreviations(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open abbreviations file: %v", err)
+	}
+	defer file.Close()
+
+	dict := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"abbreviation expansion\"", path, lineNum)
+		}
+		dict[fields[0]] = fields[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read abbreviations file: %v", err)
+	}
+	return dict, nil
+}
+
+// Normalize раскрывает сокращения и удаляет диакритику в каждом слове текста.
+// Слова без титла лишь очищаются от надстрочных знаков.
+func (n *SlavonicNormalizer) Normalize(text string) string {
+	text = norm.NFD.String(text)
+
+	var buf, word strings.Builder
+	buf.Grow(len(text))
+	flush := func() {
+		if word.Len() > 0 {
+			buf.WriteString(n.normalizeWord(word.String()))
+			word.Reset()
+		}
+	}
+
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.Is(unicode.Mn, r) || isCyrillicCombiningLetter(r) {
+			word.WriteRune(r)
+			continue
+		}
+		flush()
+		buf.WriteRune(r)
+	}
+	flush()
+
+	return buf.String()
+}
+
+func (n *SlavonicNormalizer) normalizeWord(word string) string {
+	key := abbreviationKey(word)
+	if !strings.ContainsRune(key, titlo) {
+		return key
+	}
+	if expansion, ok := n.abbreviations[key]; ok {
+		return expansion
+	}
+	// Неизвестное сокращение: оставляем буквы, убираем титло
+	return strings.ReplaceAll(key, string(titlo), "")
+}
+
+// abbreviationKey приводит слово к виду, в котором хранятся ключи словаря:
+// нижний регистр, без ударений и придыханий, надстрочные буквы перенесены
+// в строку, знаки сокращения заменены на U+0483, вариантные буквы сведены
+// к основным (общие с современными языками — только в церковнославянских
+// словах).
+func abbreviationKey(word string) string {
+	decomposed := norm.NFD.String(strings.ToLower(word))
+	slavonic := isSlavonicWord(decomposed)
+
+	var buf strings.Builder
+	buf.Grow(len(word))
+	for _, r := range decomposed {
+		switch {
+		case r == titlo || r == pokrytie || r == vzmet:
+			buf.WriteRune(titlo)
+		case isCyrillicCombiningLetter(r):
+			// Надстрочная буква («буквенное титло») раскрывается в обычную
+			buf.WriteString(combiningLetterBases[r])
+		case r == breve || r == diaeresis:
+			buf.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+			// Ударения, придыхания, камора и прочие надстрочные знаки
+		default:
+			if folded, ok := slavonicFolds[r]; ok {
+				buf.WriteString(folded)
+			} else if folded, ok := sharedFolds[r]; ok && slavonic {
+				buf.WriteString(folded)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+
+	// Сворачиваем повторные титла и сочетаем кратку/трему с буквами
+	key := norm.NFC.String(strings.ReplaceAll(buf.String(), string(titlo)+string(titlo), string(titlo)))
+	if !slavonic {
+		return key
+	}
+
+	// ї после композиции снова сводим к і
+	return strings.Map(func(r rune) rune {
+		if r == 'ї' {
+			return 'і'
+		}
+		return r
+	}, key)
+}
+
+// isCyrillicCombiningLetter сообщает, является ли r надстрочной кириллической буквой.
+func isCyrillicCombiningLetter(r rune) bool {
+	_, ok := combiningLetterBases[r]
+	return ok
+}
+
+// combiningLetterBases сопоставляет надстрочным буквам (Cyrillic Extended-A
+// и Extended-B) обычные, уже сведённые к основным начертаниям.
+var combiningLetterBases = map[rune]string{
+	'\u2DE0': "б", '\u2DE1': "в", '\u2DE2': "г", '\u2DE3': "д", '\u2DE4': "ж",
+	'\u2DE5': "з", '\u2DE6': "к", '\u2DE7': "л", '\u2DE8': "м", '\u2DE9': "н",
+	'\u2DEA': "о", '\u2DEB': "п", '\u2DEC': "р", '\u2DED': "с", '\u2DEE': "т",
+	'\u2DEF': "х", '\u2DF0': "ц", '\u2DF1': "ч", '\u2DF2': "ш", '\u2DF3': "щ",
+	'\u2DF4': "ѳ", '\u2DF5': "ст", '\u2DF6': "а", '\u2DF7': "е", '\u2DF8': "г",
+	'\u2DF9': "у", '\u2DFA': "ѣ", '\u2DFB': "ю", '\u2DFC': "я", '\u2DFD': "я",
+	'\u2DFE': "у", '\u2DFF': "ю",
+	'\uA674': "е", '\uA675': "и", '\uA676': "і", '\uA677': "у", '\uA678': "ъ",
+	'\uA679': "ы", '\uA67A': "ь", '\uA67B': "о", '\uA69E': "ф", '\uA69F': "е",
+}
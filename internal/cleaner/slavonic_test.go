@@ -0,0 +1,35 @@
+package cleaner
+
+import "testing"
+
+// Общие с современными языками буквы є, ї, ѕ сводятся к основным только в
+// словах с церковнославянскими знаками или буквами.
+func TestSlavonicNormalize(t *testing.T) {
+	n := NewSlavonicNormalizer(nil)
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"бг҃ъ", "богъ"},
+		{"гдⷭ҇ь", "господь"},
+		{"ѡ҆ми́ръ", "омиръ"},
+		{"є҆гда̀", "егда"},
+		{"мі̑ръ", "міръ"},
+		{"ї҆сꙋ́съ", "ісусъ"},
+		{"ѕѣлѡ̀", "зѣло"},
+		{"ѿ", "от"},
+		{"єдність", "єдність"},
+		{"їжак", "їжак"},
+		{"Україна", "україна"},
+		{"ѕвезда", "ѕвезда"},
+		{"наї́вний", "наївний"},
+		{"єдність бг҃ъ їжак", "єдність богъ їжак"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := n.Normalize(tt.text); got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
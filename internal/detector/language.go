@@ -0,0 +1,110 @@
+package detector
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Коды языков, которые различает Language.
+const (
+	LangRussian    = "ru"
+	LangUkrainian  = "uk"
+	LangBelarusian = "be"
+	LangSlavonic   = "cu"  // церковнославянский
+	LangEnglish    = "en"  // латиница
+	LangUnknown    = "und" // мало букв или другая письменность
+)
+
+// minLetters — меньше букв недостаточно, чтобы судить о языке.
+const minLetters = 20
+
+// Language грубо определяет язык текста по письменности и характерным
+// буквам. Этого хватает, чтобы отделить русский корпус от вкраплений
+// украинского, белорусского, церковнославянского и латиницы.
+func Language(text string) string {
+	var letters, cyrillic, latin, ukrainian, belarusian, slavonic int
+	for _, r := range text {
+		if unicode.IsMark(r) && r >= 0x0483 && r <= 0x0489 {
+			slavonic++ // титла и надстрочные знаки
+			continue
+		}
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			switch unicode.ToLower(r) {
+			case 'ї', 'є', 'ґ':
+				ukrainian++
+			case 'ў':
+				belarusian++
+			case 'ѡ', 'ѿ', 'ѧ', 'ѫ', 'ѯ', 'ѱ', 'ꙋ', 'ꙗ', 'ѹ':
+				slavonic++
+			}
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		}
+	}
+
+	switch {
+	case letters < minLetters:
+		return LangUnknown
+	case cyrillic*2 >= letters:
+		// Доли характерных букв малы даже в чистом тексте, поэтому порог
+		// считается от числа кириллических букв в промилле
+		switch {
+		case slavonic*1000 >= cyrillic*5:
+			return LangSlavonic
+		case ukrainian*1000 >= cyrillic*5:
+			return LangUkrainian
+		case belarusian*1000 >= cyrillic*5:
+			return LangBelarusian
+		}
+		return LangRussian
+	case latin*2 >= letters:
+		return LangEnglish
+	}
+	return LangUnknown
+}
+
+// Encoding определяет кодировку фрагмента исходного текста: ascii,
+// utf-8 или одну из однобайтовых кириллических (windows-1251, koi8-r,
+// ibm866) по распределению байт строчных букв.
+func Encoding(data []byte) string {
+	if utf8.Valid(data) {
+		for _, b := range data {
+			if b >= utf8.RuneSelf {
+				return "utf-8"
+			}
+		}
+		return "ascii"
+	}
+
+	// Строчные буквы, которые в тексте встречаются чаще заглавных:
+	// windows-1251 — 0xE0–0xFF, koi8-r — 0xC0–0xDF, ibm866 — 0xA0–0xAF и 0xE0–0xEF
+	var cp1251, koi8, cp866 int
+	for _, b := range data {
+		switch {
+		case b >= 0xE0 && b <= 0xEF:
+			cp1251++
+			cp866++
+		case b >= 0xF0:
+			cp1251++
+		case b >= 0xC0 && b <= 0xDF:
+			koi8++
+		case b >= 0xA0 && b <= 0xAF:
+			cp866++
+		}
+	}
+	switch {
+	case cp1251 == 0 && koi8 == 0 && cp866 == 0:
+		return "unknown"
+	case koi8 > cp1251 && koi8 > cp866:
+		return "koi8-r"
+	case cp866 > cp1251:
+		return "ibm866"
+	}
+	return "windows-1251"
+}
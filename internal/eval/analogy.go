@@ -0,0 +1,122 @@
+package eval
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/terratensor/text2glove/internal/vectors"
+)
+
+// Section — итоги раздела набора аналогий.
+type Section struct {
+	Name    string
+	Total   int // вопросов в разделе
+	Covered int // вопросов, все слова которых есть в словаре
+	Correct int // верных ответов среди покрытых
+}
+
+// Accuracy — доля верных ответов среди покрытых вопросов.
+func (s Section) Accuracy() float64 {
+	if s.Covered == 0 {
+		return 0
+	}
+	return float64(s.Correct) / float64(s.Covered)
+}
+
+// Coverage — доля вопросов, все слова которых есть в словаре.
+func (s Section) Coverage() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Covered) / float64(s.Total)
+}
+
+func (s *Section) add(other Section) {
+	s.Total += other.Total
+	s.Covered += other.Covered
+	s.Correct += other.Correct
+}
+
+// AnalogyResult — итоги по файлу аналогий.
+type AnalogyResult struct {
+	File      string
+	Sections  []Section
+	Semantic  Section // разделы без префикса gram
+	Syntactic Section // разделы gram*: грамматические аналогии
+	Total     Section
+}
+
+// Analogy проверяет векторы на файле аналогий в формате Google
+// (строки «: раздел» и вопросы «a b c d»: a относится к b, как c к d).
+// Ответ — ближайшее к b - a + c слово, кроме a, b и c (3CosAdd).
+// Векторы должны быть нормированы.
+func Analogy(e *vectors.Embeddings, path string, lowercase bool) (AnalogyResult, error) {
+	result := AnalogyResult{File: path, Semantic: Section{Name: "semantic"}, Syntactic: Section{Name: "syntactic"}, Total: Section{Name: "total"}}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return result, fmt.Errorf("failed to open analogy file: %v", err)
+	}
+	defer file.Close()
+
+	var current *Section
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			result.Sections = append(result.Sections, Section{Name: strings.TrimSpace(line[1:])})
+			current = &result.Sections[len(result.Sections)-1]
+			continue
+		}
+		if lowercase {
+			line = strings.ToLower(line)
+		}
+		words := strings.Fields(line)
+		if len(words) != 4 {
+			return result, fmt.Errorf("%s:%d: expected four words", path, lineNum)
+		}
+		if current == nil {
+			result.Sections = append(result.Sections, Section{Name: "default"})
+			current = &result.Sections[len(result.Sections)-1]
+		}
+
+		current.Total++
+		var ids [4]int
+		covered := true
+		for i, word := range words {
+			if ids[i], covered = e.Lookup(word); !covered {
+				break
+			}
+		}
+		if !covered {
+			continue
+		}
+		current.Covered++
+
+		exclude := map[int]bool{ids[0]: true, ids[1]: true, ids[2]: true}
+		best := e.Nearest(e.Analogy(ids[0], ids[1], ids[2]), 1, exclude)
+		if len(best) == 1 && best[0].Index == ids[3] {
+			current.Correct++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read analogy file: %v", err)
+	}
+
+	for _, s := range result.Sections {
+		if strings.HasPrefix(s.Name, "gram") {
+			result.Syntactic.add(s)
+		} else {
+			result.Semantic.add(s)
+		}
+		result.Total.add(s)
+	}
+	return result, nil
+}
@@ -0,0 +1,154 @@
+package eval
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/terratensor/text2glove/internal/vectors"
+)
+
+var testdata = filepath.Join("..", "..", "testdata", "eval")
+
+func TestSpearman(t *testing.T) {
+	tests := []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		{"monotone", []float64{1, 2, 3, 4}, []float64{10, 20, 25, 100}, 1},
+		{"reversed", []float64{1, 2, 3, 4}, []float64{4, 3, 2, 1}, -1},
+		// Ранги x: 1, 2.5, 2.5, 4 — средние для равных значений
+		{"tied ranks", []float64{1, 2, 2, 3}, []float64{1, 2, 3, 4}, 4.5 / math.Sqrt(4.5*5)},
+		{"ties in both", []float64{1, 1, 2, 2}, []float64{5, 5, 7, 7}, 1},
+		{"constant", []float64{1, 1, 1}, []float64{1, 2, 3}, math.NaN()},
+		{"single pair", []float64{1}, []float64{1}, math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Spearman(tt.x, tt.y)
+			if math.IsNaN(tt.want) != math.IsNaN(got) || !math.IsNaN(got) && math.Abs(got-tt.want) > 1e-12 {
+				t.Fatalf("Spearman = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Векторы на плоскости: угол между словами пары убывает с оценкой, пары
+// с равными оценками (7.0 и 8.5) состоят из одинаковых векторов, поэтому
+// ранги совпадают и корреляция равна 1.
+func TestSimilarity(t *testing.T) {
+	angles := map[string]float64{}
+	pair := func(a, b string, base, score float64) {
+		angles[a] = base
+		angles[b] = base + (10-score)*0.15
+	}
+	pair("кот", "котёнок", 0, 9.0)
+	pair("москва", "россия", 1, 7.0)
+	pair("париж", "франция", 1, 7.0)
+	pair("король", "королева", 2, 8.0)
+	pair("отец", "мать", 3, 8.5)
+	pair("брат", "сестра", 3, 8.5)
+	// «машина сестра» (0.5) — противоположные векторы
+	pair("машина", "автомобиль", angles["сестра"]+math.Pi, 9.5)
+	pair("читать", "книга", 4, 6.5)
+	pair("солнце", "луна", 5, 6.0)
+
+	var words []string
+	var values []float32
+	for word, angle := range angles {
+		words = append(words, word)
+		values = append(values, float32(math.Cos(angle)), float32(math.Sin(angle)))
+	}
+	e := vectors.New(words, 2, values)
+	e.Normalize()
+
+	result, err := Similarity(e, filepath.Join(testdata, "similarity_ru.txt"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Заголовок и комментарий не считаются; без собаки, дома, леса, реки,
+	// стола и ручки покрыты 10 пар из 20
+	if result.Pairs != 20 || result.Covered != 10 {
+		t.Fatalf("pairs %d, covered %d; want 20 and 10", result.Pairs, result.Covered)
+	}
+	if result.Coverage() != 0.5 {
+		t.Fatalf("coverage %v, want 0.5", result.Coverage())
+	}
+	if math.Abs(result.Spearman-1) > 1e-12 {
+		t.Fatalf("Spearman = %v, want 1", result.Spearman)
+	}
+}
+
+// Векторы из независимых осей: производное слово — базовое плюс ось
+// отношения раздела, поэтому 3CosAdd отвечает верно. «испания» лежит на
+// своей оси и не находится, «англии» и «принцессы» нет в словаре.
+func TestAnalogy(t *testing.T) {
+	relations := map[string][][2]string{
+		"capital": {{"москва", "россия"}, {"париж", "франция"}, {"берлин", "германия"}, {"рим", "италия"}, {"мадрид", ""}, {"лондон", ""}},
+		"family":  {{"брат", "сестра"}, {"отец", "мать"}, {"сын", "дочь"}, {"дед", "бабушка"}, {"муж", "жена"}, {"король", "королева"}, {"принц", ""}},
+		"plural":  {{"дом", "дома"}, {"город", "города"}, {"кот", "коты"}, {"лес", "леса"}, {"стол", "столы"}},
+		"past":    {{"читать", "читал"}, {"писать", "писал"}, {"играть", "играл"}, {"думать", "думал"}},
+	}
+	axes := map[string]int{}
+	axis := func(name string) int {
+		if _, ok := axes[name]; !ok {
+			axes[name] = len(axes)
+		}
+		return axes[name]
+	}
+	vecs := map[string][]int{}
+	for relation, pairs := range relations {
+		for _, p := range pairs {
+			vecs[p[0]] = []int{axis(p[0])}
+			if p[1] != "" {
+				vecs[p[1]] = []int{axis(p[0]), axis(relation)}
+			}
+		}
+	}
+	vecs["испания"] = []int{axis("испания")}
+
+	var words []string
+	var values []float32
+	for word, ones := range vecs {
+		v := make([]float32, 64)
+		for _, i := range ones {
+			v[i] = 1
+		}
+		words = append(words, word)
+		values = append(values, v...)
+	}
+	if len(axes) > 64 {
+		t.Fatalf("%d axes do not fit", len(axes))
+	}
+	e := vectors.New(words, 64, values)
+	e.Normalize()
+
+	result, err := Analogy(e, filepath.Join(testdata, "analogy_ru.txt"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Section{
+		{Name: "capital-country", Total: 10, Covered: 7, Correct: 5},
+		{Name: "family", Total: 7, Covered: 6, Correct: 6},
+		{Name: "gram-plural", Total: 6, Covered: 6, Correct: 6},
+		{Name: "gram-past", Total: 5, Covered: 5, Correct: 5},
+	}
+	if len(result.Sections) != len(want) {
+		t.Fatalf("sections %+v, want %+v", result.Sections, want)
+	}
+	for i := range want {
+		if result.Sections[i] != want[i] {
+			t.Fatalf("section %+v, want %+v", result.Sections[i], want[i])
+		}
+	}
+	for _, tt := range []struct{ got, want Section }{
+		{result.Semantic, Section{Name: "semantic", Total: 17, Covered: 13, Correct: 11}},
+		{result.Syntactic, Section{Name: "syntactic", Total: 11, Covered: 11, Correct: 11}},
+		{result.Total, Section{Name: "total", Total: 28, Covered: 24, Correct: 22}},
+	} {
+		if tt.got != tt.want {
+			t.Fatalf("%s: %+v, want %+v", tt.want.Name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package eval
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/terratensor/text2glove/internal/vectors"
+)
+
+// SimilarityResult — итоги по набору оценок близости слов.
+type SimilarityResult struct {
+	File     string
+	Pairs    int     // пар в наборе
+	Covered  int     // пар, оба слова которых есть в словаре
+	Spearman float64 // ранговая корреляция близости векторов с оценками
+}
+
+// Coverage — доля пар, оба слова которых есть в словаре.
+func (r SimilarityResult) Coverage() float64 {
+	if r.Pairs == 0 {
+		return 0
+	}
+	return float64(r.Covered) / float64(r.Pairs)
+}
+
+// Similarity сравнивает косинусную близость векторов с оценками из файла
+// (строки «слово1 слово2 оценка», разделитель — табуляция или пробелы;
+// строки, где оценка не число, например заголовок, пропускаются).
+// Векторы должны быть нормированы.
+func Similarity(e *vectors.Embeddings, path string, lowercase bool) (SimilarityResult, error) {
+	result := SimilarityResult{File: path}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return result, fmt.Errorf("failed to open similarity file: %v", err)
+	}
+	defer file.Close()
+
+	var gold, predicted []float64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if lowercase {
+			line = strings.ToLower(line)
+		}
+		var fields []string
+		if strings.Contains(line, "\t") {
+			fields = strings.Split(line, "\t")
+		} else {
+			fields = strings.Fields(line)
+		}
+		if len(fields) < 3 {
+			continue
+		}
+		score, err := strconv.ParseFloat(strings.TrimSpace(fields[2]), 64)
+		if err != nil {
+			continue
+		}
+
+		result.Pairs++
+		a, okA := e.Lookup(strings.TrimSpace(fields[0]))
+		b, okB := e.Lookup(strings.TrimSpace(fields[1]))
+		if !okA || !okB {
+			continue
+		}
+		result.Covered++
+		gold = append(gold, score)
+		predicted = append(predicted, float64(vectors.Dot(e.Vector(a), e.Vector(b))))
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read similarity file: %v", err)
+	}
+
+	result.Spearman = Spearman(gold, predicted)
+	return result, nil
+}
+
+// Spearman — коэффициент ранговой корреляции; при равных значениях
+// используются средние ранги. Возвращает NaN, если пар меньше двух.
+func Spearman(x, y []float64) float64 {
+	if len(x) != len(y) || len(x) < 2 {
+		return math.NaN()
+	}
+	return pearson(ranks(x), ranks(y))
+}
+
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+
+	result := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		rank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			result[order[k]] = rank
+		}
+		i = j + 1
+	}
+	return result
+}
+
+func pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX /= n
+	meanY /= n
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return math.NaN()
+	}
+	return cov / math.Sqrt(varX*varY)
+}
@@ -0,0 +1,240 @@
+package failures
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Policy — реакция на ошибку обработки файла.
+type Policy string
+
+const (
+	PolicySkip     Policy = "skip"      // записать в отчёт и продолжить
+	PolicyFailFast Policy = "fail-fast" // остановить обработку на первой ошибке
+	PolicyRetry    Policy = "retry"     // повторить файл до Retries раз, затем пропустить
+)
+
+// ParsePolicy проверяет имя политики.
+func ParsePolicy(s string) (Policy, error) {
+	switch policy := Policy(s); policy {
+	case PolicySkip, PolicyFailFast, PolicyRetry:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown error policy %q: expected skip|fail-fast|retry", s)
+}
+
+// Class — класс ошибки для отчёта.
+type Class string
+
+const (
+	ClassOpen    Class = "open"    // файл не открывается
+	ClassGzip    Class = "gzip"    // повреждённый архив
+	ClassScanner Class = "scanner" // ошибка чтения, в том числе слишком длинная строка
+	ClassMystem  Class = "mystem"  // сбой лемматизации
+	ClassWrite   Class = "write"   // ошибка записи вывода
+	ClassOther   Class = "other"
+)
+
+// Error — ошибка обработки с классом.
+type Error struct {
+	Class Class
+	Err   error
+}
+
+func (e *Error) Error() string { return e.Err.Error() }
+func (e *Error) Unwrap() error { return e.Err }
+
+// Wrap присваивает ошибке класс.
+func Wrap(class Class, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Class: class, Err: err}
+}
+
+// ClassOf возвращает класс ошибки; ошибки без класса — ClassOther,
+// bufio.ErrTooLong — ClassScanner.
+func ClassOf(err error) Class {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Class
+	}
+	if errors.Is(err, bufio.ErrTooLong) {
+		return ClassScanner
+	}
+	return ClassOther
+}
+
+// retryable — классы ошибок, которые могут пройти при повторе: ошибки
+// данных (повреждённый архив, длинная строка) при повторе не исчезнут.
+var retryable = map[Class]bool{
+	ClassOpen:   true,
+	ClassMystem: true,
+	ClassOther:  true,
+}
+
+// Failure — запись отчёта об ошибке.
+type Failure struct {
+	File     string    `json:"file"`
+	Class    Class     `json:"class"`
+	Error    string    `json:"error"`
+	Attempts int       `json:"attempts"`
+	Time     time.Time `json:"time"`
+}
+
+// Tracker применяет политику к ошибкам рабочих и писателя, ведёт отчёт
+// (JSON Lines, запись на ошибку) и останавливает обработку через cancel,
+// когда политика или порог этого требуют.
+type Tracker struct {
+	policy    Policy
+	retries   int
+	maxErrors int // 0 — без порога
+	cancel    context.CancelFunc
+
+	mu      sync.Mutex
+	report  *os.File
+	counts  map[Class]int
+	total   int
+	stopped error // причина остановки; nil — обработка не останавливалась
+}
+
+// NewTracker создаёт трекер ошибок; reportPath может быть пустым.
+func NewTracker(policy Policy, retries, maxErrors int, reportPath string, cancel context.CancelFunc) (*Tracker, error) {
+	t := &Tracker{
+		policy:    policy,
+		retries:   retries,
+		maxErrors: maxErrors,
+		cancel:    cancel,
+		counts:    make(map[Class]int),
+	}
+	if reportPath != "" {
+		file, err := os.Create(reportPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create error report: %v", err)
+		}
+		t.report = file
+	}
+	return t, nil
+}
+
+// Retry сообщает, нужно ли повторить файл после attempt неудачных попыток.
+func (t *Tracker) Retry(err error, attempt int) bool {
+	return t.policy == PolicyRetry && attempt <= t.retries && retryable[ClassOf(err)]
+}
+
+// Fail записывает ошибку файла и по политике останавливает обработку.
+func (t *Tracker) Fail(file string, err error, attempts int) {
+	t.record(file, err, attempts, t.policy == PolicyFailFast)
+}
+
+// Abort записывает ошибку, после которой продолжать нельзя (например,
+// ошибку записи вывода), и останавливает обработку при любой политике.
+func (t *Tracker) Abort(file string, err error) {
+	t.record(file, err, 1, true)
+}
+
+// WriteErrors — приёмник ошибок записи вывода для писателя.
+type WriteErrors struct {
+	tracker *Tracker
+}
+
+// WriteErrors возвращает приёмник ошибок записи вывода.
+func (t *Tracker) WriteErrors() WriteErrors {
+	return WriteErrors{tracker: t}
+}
+
+// Abort записывает ошибку записи с классом ClassWrite и останавливает
+// обработку.
+func (w WriteErrors) Abort(file string, err error) {
+	w.tracker.Abort(file, Wrap(ClassWrite, err))
+}
+
+func (t *Tracker) record(file string, err error, attempts int, stop bool) {
+	failure := Failure{
+		File:     file,
+		Class:    ClassOf(err),
+		Error:    err.Error(),
+		Attempts: attempts,
+		Time:     time.Now().UTC(),
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts[failure.Class]++
+	t.total++
+	if t.report != nil {
+		if data, jerr := json.Marshal(failure); jerr == nil {
+			t.report.Write(append(data, '\n'))
+		}
+	}
+
+	switch {
+	case t.stopped != nil:
+	case stop:
+		t.stopped = fmt.Errorf("%s: %v", file, err)
+	case t.maxErrors > 0 && t.total > t.maxErrors:
+		t.stopped = fmt.Errorf("more than %d files failed", t.maxErrors)
+	}
+	if t.stopped != nil {
+		t.cancel()
+	}
+}
+
+// Stopped возвращает причину остановки обработки или nil.
+func (t *Tracker) Stopped() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stopped
+}
+
+// Total возвращает число ошибок.
+func (t *Tracker) Total() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.total
+}
+
+// Counts возвращает число ошибок по классам.
+func (t *Tracker) Counts() map[Class]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := make(map[Class]int, len(t.counts))
+	for class, n := range t.counts {
+		counts[class] = n
+	}
+	return counts
+}
+
+// Summary возвращает число ошибок по классам в виде «gzip 2, open 1».
+func (t *Tracker) Summary() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	classes := make([]string, 0, len(t.counts))
+	for class := range t.counts {
+		classes = append(classes, string(class))
+	}
+	sort.Strings(classes)
+	summary := ""
+	for i, class := range classes {
+		if i > 0 {
+			summary += ", "
+		}
+		summary += fmt.Sprintf("%s %d", class, t.counts[Class(class)])
+	}
+	return summary
+}
+
+// Close закрывает файл отчёта.
+func (t *Tracker) Close() error {
+	if t.report == nil {
+		return nil
+	}
+	return t.report.Close()
+}
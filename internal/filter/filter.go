@@ -0,0 +1,130 @@
+package filter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/terratensor/text2glove/internal/cleaner"
+)
+
+// Stats — число удалённых токенов по причинам.
+type Stats struct {
+	Stopwords uint64
+	Short     uint64
+	Long      uint64
+}
+
+// Total возвращает общее число удалённых токенов.
+func (s Stats) Total() uint64 {
+	return s.Stopwords + s.Short + s.Long
+}
+
+// LemmatizedMaxLength — предел длины токена по умолчанию при лемматизации:
+// более длинные токены mystem обрабатывает плохо
+const LemmatizedMaxLength = 100
+
+// TokenFilter удаляет стоп-слова и токены вне допустимой длины.
+// Безопасен для использования из нескольких горутин.
+type TokenFilter struct {
+	minLength int // в рунах; 0 — без ограничения
+	maxLength int // в рунах; 0 — без ограничения
+	stopwords map[string]struct{}
+
+	logFile  *os.File
+	logMutex sync.Mutex
+
+	stopwordsRemoved atomic.Uint64
+	shortRemoved     atomic.Uint64
+	longRemoved      atomic.Uint64
+}
+
+// New создаёт фильтр. Если logPath не пуст, удалённые по длине токены
+// записываются в этот файл (вместе с именем исходного файла).
+func New(minLength, maxLength int, stopwords map[string]struct{}, logPath string) (*TokenFilter, error) {
+	f := &TokenFilter{
+		minLength: minLength,
+		maxLength: maxLength,
+		stopwords: stopwords,
+	}
+
+	if logPath != "" {
+		// Создаем директории, если их нет
+		if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create log directory: %v", err)
+		}
+
+		file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %v", err)
+		}
+		f.logFile = file
+	}
+	return f, nil
+}
+
+func (f *TokenFilter) Close() {
+	if f.logFile != nil {
+		f.logFile.Close()
+	}
+}
+
+// Apply возвращает текст без отфильтрованных токенов.
+// Токены-заменители чисел (<NUM>) не фильтруются.
+func (f *TokenFilter) Apply(text, filename string) string {
+	tokens := strings.Fields(text)
+	kept := tokens[:0]
+	for _, token := range tokens {
+		if f.keep(token, filename) {
+			kept = append(kept, token)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+func (f *TokenFilter) keep(token, filename string) bool {
+	if cleaner.IsPlaceholder(token) {
+		return true
+	}
+
+	length := utf8.RuneCountInString(token)
+	switch {
+	case f.minLength > 0 && length < f.minLength:
+		f.shortRemoved.Add(1)
+		return false
+	case f.maxLength > 0 && length > f.maxLength:
+		f.longRemoved.Add(1)
+		f.logToken(filename, token, "LONG")
+		return false
+	}
+
+	if _, ok := f.stopwords[token]; ok {
+		f.stopwordsRemoved.Add(1)
+		return false
+	}
+	return true
+}
+
+func (f *TokenFilter) Stats() Stats {
+	return Stats{
+		Stopwords: f.stopwordsRemoved.Load(),
+		Short:     f.shortRemoved.Load(),
+		Long:      f.longRemoved.Load(),
+	}
+}
+
+func (f *TokenFilter) logToken(filename, token, level string) {
+	if f.logFile == nil {
+		return
+	}
+
+	f.logMutex.Lock()
+	defer f.logMutex.Unlock()
+
+	logLine := fmt.Sprintf("[%s] %s: %s\n", level, filename, token)
+	f.logFile.WriteString(logLine)
+}
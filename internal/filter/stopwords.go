@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// builtinStopwords — встроенные списки служебных слов (нижний регистр).
+// Русский список включает и словоформы, и леммы, чтобы работать
+// как до лемматизации, так и после неё.
+var builtinStopwords = map[string]string{
+	"ru": `а без более бы был была были было быть в вам вас весь во вот все всего всех вы
+где да даже для до его ее её если есть еще ещё же за здесь и из или им их к как
+какая какой когда кто ли либо меня мне мной мы на над нам нас не него нее неё нет
+ни нибудь никогда ним них ничего но ну о об один он она они оно опять от очень
+по под после потом потому при про раз с сам сама само свой себе себя со так также
+такой там тебя тем теперь то тогда того тоже той только том ты у уж уже хоть
+чего чей чем что чтобы чье чьё чья эта эти это этого этой этом этот я
+который которая которое которые мой моя мое моё мои твой твоя твое твоё твои
+наш наша наше наши ваш ваша ваше ваши тот та те этот весь вся всё всю всем`,
+	"en": `a about above after again against all am an and any are as at be because been
+before being below between both but by can could did do does doing down during
+each few for from further had has have having he her here hers herself him himself
+his how i if in into is it its itself just me more most my myself no nor not of
+off on once only or other our ours ourselves out over own same she should so some
+such than that the their theirs them themselves then there these they this those
+through to too under until up very was we were what when where which while who whom
+why will with would you your yours yourself yourselves`,
+}
+
+// BuiltinStopwords возвращает встроенные списки стоп-слов для языков ("ru", "en").
+func BuiltinStopwords(langs ...string) (map[string]struct{}, error) {
+	stopwords := make(map[string]struct{})
+	for _, lang := range langs {
+		list, ok := builtinStopwords[strings.ToLower(strings.TrimSpace(lang))]
+		if !ok {
+			return nil, fmt.Errorf("no built-in stopword list for language %q (available: ru, en)", lang)
+		}
+		for _, word := range strings.Fields(list) {
+			stopwords[word] = struct{}{}
+		}
+	}
+	return stopwords, nil
+}
+
+// LoadStopwords добавляет в stopwords слова из файла: по одному в строке,
+// строки, начинающиеся с #, пропускаются.
+func LoadStopwords(path string, stopwords map[string]struct{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open stopwords file: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word != "" && !strings.HasPrefix(word, "#") {
+			stopwords[word] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stopwords file: %v", err)
+	}
+	return nil
+}
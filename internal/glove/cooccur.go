@@ -0,0 +1,399 @@
+package glove
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/terratensor/text2glove/internal/vocab"
+)
+
+// DefaultMemory — мягкий предел памяти по умолчанию, ГБ.
+const DefaultMemory = 4.0
+
+// CooccurOptions задаёт параметры подсчёта совместной встречаемости.
+type CooccurOptions struct {
+	WindowSize        int     // число слов контекста слева (и справа при Symmetric)
+	Symmetric         bool    // учитывать контекст с обеих сторон
+	DistanceWeighting bool    // вес пары 1/d, где d — расстояние между словами
+	Memory            float64 // мягкий предел памяти, ГБ (0 — DefaultMemory)
+	MaxProduct        int64   // 0 — вычислить по Memory
+	OverflowLength    int64   // 0 — вычислить по Memory
+	TempDir           string  // каталог для временных файлов (пусто — системный)
+}
+
+// CooccurStats — итоги подсчёта.
+type CooccurStats struct {
+	Tokens    uint64 // всего токенов в корпусе
+	VocabSize int    // слов в словаре
+	TableSize int64  // элементов в плотной таблице
+	Chunks    int    // сброшенных на диск частей
+	Records   int64  // записей в результате
+}
+
+// Limits оценивает max_product и overflow_length по пределу памяти
+// так же, как cooccur из GloVe.
+func Limits(memory float64) (maxProduct, overflowLength int64) {
+	rlimit := 0.85 * memory * 1073741824 / RecordSize
+	n := 1e5
+	for math.Abs(rlimit-n*(math.Log(n)+0.1544313298)) > 1e-3 {
+		n = rlimit / (math.Log(n) + 0.1544313298)
+	}
+	return int64(n), int64(rlimit / 6)
+}
+
+// Cooccur считает взвешенную совместную встречаемость слов словаря
+// vocabPath в корпусах paths и пишет записи CREC в out, отсортированные
+// по (word1, word2), как cooccur из GloVe.
+//
+// Пары слов, произведение рангов которых меньше max_product, копятся
+// в плотной таблице; остальные — в буфере, который при заполнении
+// сортируется и сбрасывается во временный файл. В конце таблица и все
+// части сливаются с суммированием одинаковых пар. Окно не пересекает
+// границы строк, слова вне словаря пропускаются и в окне не учитываются.
+func Cooccur(vocabPath string, paths []string, out io.Writer, opts CooccurOptions) (CooccurStats, error) {
+	var stats CooccurStats
+	if opts.WindowSize <= 0 {
+		return stats, fmt.Errorf("window size must be positive")
+	}
+	if opts.Memory <= 0 {
+		opts.Memory = DefaultMemory
+	}
+	maxProduct, overflowLength := Limits(opts.Memory)
+	if opts.MaxProduct > 0 {
+		maxProduct = opts.MaxProduct
+	}
+	if opts.OverflowLength > 0 {
+		overflowLength = opts.OverflowLength
+	}
+
+	entries, err := vocab.Read(vocabPath)
+	if err != nil {
+		return stats, err
+	}
+	ranks := make(map[string]int64, len(entries))
+	for i, e := range entries {
+		if _, ok := ranks[e.Word]; !ok {
+			ranks[e.Word] = int64(i + 1)
+		}
+	}
+	vocabSize := int64(len(entries))
+	stats.VocabSize = len(entries)
+
+	// lookup[a-1] — начало строки слова a в плотной таблице
+	lookup := make([]int64, vocabSize+1)
+	lookup[0] = 1
+	for a := int64(1); a <= vocabSize; a++ {
+		if lookup[a] = maxProduct / a; lookup[a] < vocabSize {
+			lookup[a] += lookup[a-1]
+		} else {
+			lookup[a] = lookup[a-1] + vocabSize
+		}
+	}
+	stats.TableSize = lookup[vocabSize]
+	table := make([]float64, lookup[vocabSize])
+
+	tempDir, err := os.MkdirTemp(opts.TempDir, "text2glove-cooccur-")
+	if err != nil {
+		return stats, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var chunks []string
+	var overflow []Record
+	flush := func() error {
+		if len(overflow) == 0 {
+			return nil
+		}
+		path := filepath.Join(tempDir, fmt.Sprintf("overflow_%04d.bin", len(chunks)+1))
+		if err := writeChunk(path, overflow); err != nil {
+			return err
+		}
+		chunks = append(chunks, path)
+		overflow = overflow[:0]
+		return nil
+	}
+
+	window := int64(opts.WindowSize)
+	history := make([]int64, window)
+	var j int64
+	add := func(word []byte) error {
+		stats.Tokens++
+		w2, ok := ranks[string(word)]
+		if !ok {
+			return nil
+		}
+		if int64(len(overflow)) >= overflowLength-window {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		lo := int64(0)
+		if j > window {
+			lo = j - window
+		}
+		for k := j - 1; k >= lo; k-- {
+			w1 := history[k%window]
+			weight := 1.0
+			if opts.DistanceWeighting {
+				weight = 1.0 / float64(j-k)
+			}
+			if w1 < maxProduct/w2 {
+				table[lookup[w1-1]+w2-2] += weight
+				if opts.Symmetric {
+					table[lookup[w2-1]+w1-2] += weight
+				}
+				continue
+			}
+			overflow = append(overflow, Record{Word1: int32(w1), Word2: int32(w2), Val: weight})
+			if opts.Symmetric {
+				overflow = append(overflow, Record{Word1: int32(w2), Word2: int32(w1), Val: weight})
+			}
+		}
+		history[j%window] = w2
+		j++
+		return nil
+	}
+	newline := func() { j = 0 }
+
+	for _, path := range paths {
+		if err := scanCorpus(path, add, newline); err != nil {
+			return stats, err
+		}
+		newline()
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+	stats.Chunks = len(chunks)
+
+	sources := []recordSource{&tableSource{table: table, lookup: lookup, x: 1}}
+	for _, path := range chunks {
+		file, err := os.Open(path)
+		if err != nil {
+			return stats, fmt.Errorf("failed to open overflow file: %v", err)
+		}
+		defer file.Close()
+		sources = append(sources, &fileSource{reader: NewRecordReader(file)})
+	}
+
+	w := NewRecordWriter(out)
+	if err := mergeRecords(sources, w); err != nil {
+		return stats, err
+	}
+	if err := w.Flush(); err != nil {
+		return stats, fmt.Errorf("failed to write cooccurrences: %v", err)
+	}
+	stats.Records = w.Count()
+	return stats, nil
+}
+
+// scanCorpus разбивает корпус на слова так же, как get_word в GloVe:
+// разделители — пробел, табуляция и перевод строки, '\r' игнорируется.
+func scanCorpus(path string, word func([]byte) error, newline func()) error {
+	r, err := vocab.OpenCorpus(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	br := bufio.NewReaderSize(r, 1024*1024)
+	var buf []byte
+	for {
+		ch, err := br.ReadByte()
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read corpus %s: %v", path, err)
+		}
+		eof := err == io.EOF
+		if !eof && ch == '\r' {
+			continue
+		}
+		if eof || ch == ' ' || ch == '\t' || ch == '\n' {
+			if len(buf) > 0 {
+				if err := word(buf); err != nil {
+					return err
+				}
+				buf = buf[:0]
+			}
+			if eof {
+				return nil
+			}
+			if ch == '\n' {
+				newline()
+			}
+			continue
+		}
+		buf = append(buf, ch)
+	}
+}
+
+// writeChunk сортирует буфер и пишет его во временный файл,
+// суммируя одинаковые пары (write_chunk в cooccur.c).
+func writeChunk(path string, records []Record) error {
+	sort.SliceStable(records, func(a, b int) bool { return compare(records[a], records[b]) < 0 })
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create overflow file: %v", err)
+	}
+	defer file.Close()
+
+	w := NewRecordWriter(file)
+	old := records[0]
+	for _, r := range records[1:] {
+		if r.Word1 == old.Word1 && r.Word2 == old.Word2 {
+			old.Val += r.Val
+			continue
+		}
+		w.Write(old)
+		old = r
+	}
+	w.Write(old)
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write overflow file: %v", err)
+	}
+	return nil
+}
+
+// recordSource — упорядоченный поток записей для слияния.
+type recordSource interface {
+	next() (Record, bool, error)
+}
+
+// tableSource перебирает ненулевые элементы плотной таблицы.
+type tableSource struct {
+	table  []float64
+	lookup []int64
+	x, y   int64
+}
+
+func (s *tableSource) next() (Record, bool, error) {
+	for ; s.x < int64(len(s.lookup)); s.x, s.y = s.x+1, 0 {
+		for s.y < s.lookup[s.x]-s.lookup[s.x-1] {
+			s.y++
+			if val := s.table[s.lookup[s.x-1]-2+s.y]; val != 0 {
+				return Record{Word1: int32(s.x), Word2: int32(s.y), Val: val}, true, nil
+			}
+		}
+	}
+	return Record{}, false, nil
+}
+
+type fileSource struct {
+	reader *RecordReader
+}
+
+func (s *fileSource) next() (Record, bool, error) {
+	r, err := s.reader.Read()
+	if err == io.EOF {
+		return Record{}, false, nil
+	}
+	if err != nil {
+		return Record{}, false, err
+	}
+	return r, true, nil
+}
+
+// mergeRecords сливает отсортированные потоки, суммируя одинаковые пары.
+// Очередь повторяет merge_files из cooccur.c, чтобы одинаковые пары
+// складывались в том же порядке и результат совпадал до бита.
+func mergeRecords(sources []recordSource, w *RecordWriter) error {
+	pq := make(recordQueue, len(sources))
+	size := 0
+	for id, src := range sources {
+		r, ok, err := src.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			size++
+			pq.insert(queueItem{record: r, id: id}, size)
+		}
+	}
+	if size == 0 {
+		return nil
+	}
+
+	// pop извлекает вершину очереди и добавляет следующую запись её потока
+	pop := func() (queueItem, error) {
+		top := pq[0]
+		pq.delete(size)
+		r, ok, err := sources[top.id].next()
+		if err != nil {
+			return top, err
+		}
+		if ok {
+			pq.insert(queueItem{record: r, id: top.id}, size)
+		} else {
+			size--
+		}
+		return top, nil
+	}
+
+	first, err := pop()
+	if err != nil {
+		return err
+	}
+	old := first.record
+	for size > 0 {
+		item, err := pop()
+		if err != nil {
+			return err
+		}
+		if item.record.Word1 == old.Word1 && item.record.Word2 == old.Word2 {
+			old.Val += item.record.Val
+			continue
+		}
+		if err := w.Write(old); err != nil {
+			return err
+		}
+		old = item.record
+	}
+	return w.Write(old)
+}
+
+type queueItem struct {
+	record Record
+	id     int
+}
+
+// recordQueue — двоичная куча с операциями insert и delete из cooccur.c.
+type recordQueue []queueItem
+
+func (pq recordQueue) insert(item queueItem, size int) {
+	j := size - 1
+	pq[j] = item
+	for j > 0 {
+		p := (j - 1) / 2
+		if compare(pq[p].record, pq[j].record) <= 0 {
+			break
+		}
+		pq[p], pq[j] = pq[j], pq[p]
+		j = p
+	}
+}
+
+func (pq recordQueue) delete(size int) {
+	p := 0
+	pq[p] = pq[size-1]
+	for j := 2*p + 1; j < size-1; j = 2*p + 1 {
+		if j == size-2 {
+			if compare(pq[p].record, pq[j].record) > 0 {
+				pq[p], pq[j] = pq[j], pq[p]
+			}
+			return
+		}
+		if compare(pq[j].record, pq[j+1].record) >= 0 {
+			j++
+		}
+		if compare(pq[p].record, pq[j].record) <= 0 {
+			return
+		}
+		pq[p], pq[j] = pq[j], pq[p]
+		p = j
+	}
+}
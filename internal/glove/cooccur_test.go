@@ -0,0 +1,66 @@
+package glove
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Эталонные файлы testdata/glove получены программой cooccur из GloVe на
+// том же корпусе и словаре (см. testdata/glove/README.md). max_product и
+// overflow_length малы, чтобы часть пар шла через буфер переполнения,
+// сбрасывалась в несколько временных файлов и сливалась с плотной таблицей.
+func TestCooccurMatchesGloVe(t *testing.T) {
+	dir := filepath.Join("..", "..", "testdata", "glove")
+	tests := []struct {
+		name      string
+		reference string
+		opts      CooccurOptions
+	}{
+		{
+			name:      "symmetric weighted",
+			reference: "cooccurrence.bin",
+			opts:      CooccurOptions{WindowSize: 2, Symmetric: true, DistanceWeighting: true},
+		},
+		{
+			name:      "left unweighted",
+			reference: "cooccurrence.left.bin",
+			opts:      CooccurOptions{WindowSize: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := os.ReadFile(filepath.Join(dir, tt.reference))
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := tt.opts
+			opts.MaxProduct = 20
+			opts.OverflowLength = 200
+			opts.TempDir = t.TempDir()
+
+			var got bytes.Buffer
+			stats, err := Cooccur(filepath.Join(dir, "vocab.txt"), []string{filepath.Join(dir, "corpus.txt")}, &got, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Chunks < 2 {
+				t.Fatalf("overflow buffer spilled %d times, the fixture must exercise the merge", stats.Chunks)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Fatalf("output differs from GloVe cooccur: %d bytes, want %d (first difference at byte %d)",
+					got.Len(), len(want), firstDifference(got.Bytes(), want))
+			}
+		})
+	}
+}
+
+func firstDifference(a, b []byte) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return min(len(a), len(b))
+}
@@ -0,0 +1,90 @@
+package glove
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// RecordSize — размер записи CREC в байтах: int32, int32, float64.
+const RecordSize = 16
+
+// Record — запись совместной встречаемости в формате CREC из GloVe.
+// Номера слов начинаются с 1 и совпадают с их позицией в словаре.
+type Record struct {
+	Word1 int32
+	Word2 int32
+	Val   float64
+}
+
+// compare упорядочивает записи как compare_crec в cooccur.c.
+func compare(a, b Record) int {
+	if a.Word1 != b.Word1 {
+		return int(a.Word1 - b.Word1)
+	}
+	return int(a.Word2 - b.Word2)
+}
+
+func encodeRecord(buf []byte, r Record) {
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(r.Word1))
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(r.Word2))
+	binary.LittleEndian.PutUint64(buf[8:16], math.Float64bits(r.Val))
+}
+
+func decodeRecord(buf []byte) Record {
+	return Record{
+		Word1: int32(binary.LittleEndian.Uint32(buf[0:4])),
+		Word2: int32(binary.LittleEndian.Uint32(buf[4:8])),
+		Val:   math.Float64frombits(binary.LittleEndian.Uint64(buf[8:16])),
+	}
+}
+
+// RecordWriter пишет записи CREC (little-endian, как на x86).
+type RecordWriter struct {
+	w   *bufio.Writer
+	buf [RecordSize]byte
+	n   int64
+}
+
+func NewRecordWriter(w io.Writer) *RecordWriter {
+	return &RecordWriter{w: bufio.NewWriterSize(w, 1024*1024)}
+}
+
+func (rw *RecordWriter) Write(r Record) error {
+	encodeRecord(rw.buf[:], r)
+	_, err := rw.w.Write(rw.buf[:])
+	rw.n++
+	return err
+}
+
+// Count возвращает число записанных записей.
+func (rw *RecordWriter) Count() int64 {
+	return rw.n
+}
+
+func (rw *RecordWriter) Flush() error {
+	return rw.w.Flush()
+}
+
+// RecordReader читает записи CREC.
+type RecordReader struct {
+	r   *bufio.Reader
+	buf [RecordSize]byte
+}
+
+func NewRecordReader(r io.Reader) *RecordReader {
+	return &RecordReader{r: bufio.NewReaderSize(r, 1024*1024)}
+}
+
+// Read возвращает следующую запись или io.EOF.
+func (rr *RecordReader) Read() (Record, error) {
+	if _, err := io.ReadFull(rr.r, rr.buf[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Record{}, fmt.Errorf("truncated cooccurrence record")
+		}
+		return Record{}, err
+	}
+	return decodeRecord(rr.buf[:]), nil
+}
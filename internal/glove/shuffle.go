@@ -0,0 +1,166 @@
+package glove
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+)
+
+// ShuffleOptions задаёт параметры перемешивания.
+type ShuffleOptions struct {
+	Memory    float64 // мягкий предел памяти, ГБ (0 — DefaultMemory)
+	ArraySize int64   // записей в памяти; 0 — вычислить по Memory
+	Seed      int64   // зерно генератора: одинаковое зерно даёт одинаковый результат
+	TempDir   string  // каталог для временных файлов (пусто — системный)
+}
+
+// ShuffleStats — итоги перемешивания.
+type ShuffleStats struct {
+	Records int64 // перемешано записей
+	Chunks  int   // временных файлов
+}
+
+// Shuffle перемешивает записи CREC из in и пишет их в out, как shuffle
+// из GloVe: вход читается порциями по ArraySize записей, каждая порция
+// перемешивается в памяти и сбрасывается во временный файл; затем из всех
+// файлов поочерёдно берётся по ArraySize/N записей, они перемешиваются
+// и записываются в результат. Если вход помещается в одну порцию,
+// временные файлы не создаются.
+func Shuffle(in io.Reader, out io.Writer, opts ShuffleOptions) (ShuffleStats, error) {
+	var stats ShuffleStats
+	if opts.Memory <= 0 {
+		opts.Memory = DefaultMemory
+	}
+	arraySize := opts.ArraySize
+	if arraySize <= 0 {
+		arraySize = int64(0.95 * opts.Memory * 1073741824 / RecordSize)
+	}
+	rng := rand.New(rand.NewSource(opts.Seed))
+
+	reader := NewRecordReader(in)
+	array := make([]Record, 0, min(arraySize, 1<<20))
+	eof := false
+	fill := func() error {
+		array = array[:0]
+		for int64(len(array)) < arraySize {
+			r, err := reader.Read()
+			if err == io.EOF {
+				eof = true
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read cooccurrences: %v", err)
+			}
+			array = append(array, r)
+		}
+		return nil
+	}
+
+	if err := fill(); err != nil {
+		return stats, err
+	}
+	w := NewRecordWriter(out)
+	if eof {
+		shuffleRecords(rng, array)
+		if err := writeRecords(w, array); err != nil {
+			return stats, err
+		}
+		stats.Records = w.Count()
+		return stats, nil
+	}
+
+	tempDir, err := os.MkdirTemp(opts.TempDir, "text2glove-shuffle-")
+	if err != nil {
+		return stats, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var chunks []*os.File
+	defer func() {
+		for _, file := range chunks {
+			file.Close()
+		}
+	}()
+	for len(array) > 0 {
+		shuffleRecords(rng, array)
+		path := filepath.Join(tempDir, fmt.Sprintf("temp_shuffle_%04d.bin", len(chunks)))
+		file, err := os.Create(path)
+		if err != nil {
+			return stats, fmt.Errorf("failed to create temp file: %v", err)
+		}
+		chunks = append(chunks, file)
+		if err := writeRecords(NewRecordWriter(file), array); err != nil {
+			return stats, err
+		}
+		if eof {
+			break
+		}
+		if err := fill(); err != nil {
+			return stats, err
+		}
+	}
+	stats.Chunks = len(chunks)
+
+	// Сливаем части, перемешивая записи между ними
+	readers := make([]*RecordReader, len(chunks))
+	for i, file := range chunks {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return stats, fmt.Errorf("failed to rewind temp file: %v", err)
+		}
+		readers[i] = NewRecordReader(file)
+	}
+	perChunk := max(arraySize/int64(len(chunks)), 1)
+	for {
+		array = array[:0]
+		for i, r := range readers {
+			if r == nil {
+				continue
+			}
+			for k := int64(0); k < perChunk; k++ {
+				rec, err := r.Read()
+				if err == io.EOF {
+					readers[i] = nil
+					break
+				}
+				if err != nil {
+					return stats, fmt.Errorf("failed to read temp file: %v", err)
+				}
+				array = append(array, rec)
+			}
+		}
+		if len(array) == 0 {
+			break
+		}
+		shuffleRecords(rng, array)
+		if err := writeRecords(w, array); err != nil {
+			return stats, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return stats, fmt.Errorf("failed to write shuffled records: %v", err)
+	}
+	stats.Records = w.Count()
+	return stats, nil
+}
+
+// shuffleRecords — перестановка Фишера — Йетса.
+func shuffleRecords(rng *rand.Rand, records []Record) {
+	for i := len(records) - 1; i > 0; i-- {
+		j := rng.Int63n(int64(i) + 1)
+		records[i], records[j] = records[j], records[i]
+	}
+}
+
+func writeRecords(w *RecordWriter, records []Record) error {
+	for _, r := range records {
+		if err := w.Write(r); err != nil {
+			return fmt.Errorf("failed to write records: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write records: %v", err)
+	}
+	return nil
+}
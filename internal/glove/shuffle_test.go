@@ -0,0 +1,85 @@
+package glove
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// Перемешивание с одним зерном воспроизводимо побайтно, а результат —
+// перестановка входа: ни одна запись не теряется и не повторяется.
+func TestShuffleIsDeterministicPermutation(t *testing.T) {
+	var records []Record
+	var input bytes.Buffer
+	w := NewRecordWriter(&input)
+	for i := 0; i < 1000; i++ {
+		r := Record{Word1: int32(i/37 + 1), Word2: int32(i%37 + 1), Val: float64(i) / 7}
+		records = append(records, r)
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		arraySize int64
+		chunks    bool
+	}{
+		{"in memory", 4096, false},
+		{"temp files", 64, true},
+		{"uneven temp files", 333, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shuffle := func(seed int64) []byte {
+				var out bytes.Buffer
+				stats, err := Shuffle(bytes.NewReader(input.Bytes()), &out, ShuffleOptions{ArraySize: tt.arraySize, Seed: seed, TempDir: t.TempDir()})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if stats.Records != int64(len(records)) {
+					t.Fatalf("shuffled %d records, want %d", stats.Records, len(records))
+				}
+				if (stats.Chunks > 1) != tt.chunks {
+					t.Fatalf("%d temp files with array size %d", stats.Chunks, tt.arraySize)
+				}
+				return out.Bytes()
+			}
+
+			first, second := shuffle(1), shuffle(1)
+			if !bytes.Equal(first, second) {
+				t.Fatal("same seed gave different output")
+			}
+			if bytes.Equal(first, shuffle(2)) {
+				t.Fatal("different seeds gave the same output")
+			}
+			if bytes.Equal(first, input.Bytes()) {
+				t.Fatal("output is not shuffled")
+			}
+
+			got := decodeAll(t, first)
+			if len(got) != len(records) {
+				t.Fatalf("output has %d records, want %d", len(got), len(records))
+			}
+			slices.SortFunc(got, compare)
+			if !slices.Equal(got, records) {
+				t.Fatal("output is not a permutation of the input")
+			}
+		})
+	}
+}
+
+func decodeAll(t *testing.T, data []byte) []Record {
+	t.Helper()
+	if len(data)%RecordSize != 0 {
+		t.Fatalf("output has %d bytes, not a multiple of %d", len(data), RecordSize)
+	}
+	records := make([]Record, 0, len(data)/RecordSize)
+	for i := 0; i < len(data); i += RecordSize {
+		records = append(records, decodeRecord(data[i:]))
+	}
+	return records
+}
@@ -0,0 +1,472 @@
+package glove
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TrainOptions задаёт параметры обучения GloVe.
+type TrainOptions struct {
+	Threads    int     // число горутин
+	Iterations int     // общее число итераций
+	Eta        float64 // начальная скорость обучения
+	Alpha      float64 // показатель степени весовой функции
+	XMax       float64 // порог весовой функции
+	GradClip   float64 // ограничение компоненты градиента
+
+	// Checkpoint — файл контрольной точки, сохраняемой каждые
+	// CheckpointEvery итераций (пусто или 0 — не сохранять).
+	Checkpoint      string
+	CheckpointEvery int
+}
+
+// TrainSource — файл записей и параметры обучения, от которых зависит
+// результат. Сохраняется в контрольной точке: продолжать обучение с
+// другими нельзя.
+type TrainSource struct {
+	Input     string // абсолютный путь к файлу записей
+	InputSize int64  // его размер в байтах
+	Eta       float64
+	Alpha     float64
+	XMax      float64
+}
+
+// NewTrainSource описывает обучение на файле path с параметрами opts.
+func NewTrainSource(path string, opts TrainOptions) (TrainSource, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return TrainSource{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return TrainSource{}, fmt.Errorf("failed to open cooccurrence file: %v", err)
+	}
+	return TrainSource{Input: abs, InputSize: info.Size(), Eta: opts.Eta, Alpha: opts.Alpha, XMax: opts.XMax}, nil
+}
+
+// diff возвращает описания различий s и other.
+func (s TrainSource) diff(other TrainSource) []string {
+	var changed []string
+	if s.Input != other.Input || s.InputSize != other.InputSize {
+		changed = append(changed, fmt.Sprintf("input %s (%d bytes) != %s (%d bytes)", s.Input, s.InputSize, other.Input, other.InputSize))
+	}
+	for _, p := range []struct {
+		name string
+		a, b float64
+	}{{"eta", s.Eta, other.Eta}, {"alpha", s.Alpha, other.Alpha}, {"x_max", s.XMax, other.XMax}} {
+		if p.a != p.b {
+			changed = append(changed, fmt.Sprintf("%s %g != %g", p.name, p.a, p.b))
+		}
+	}
+	return changed
+}
+
+// Model — параметры GloVe: векторы слов и контекстов со смещениями
+// (W) и суммы квадратов градиентов AdaGrad (GradSq), расположенные
+// так же, как в glove.c: сначала vocabSize векторов слов, затем
+// vocabSize векторов контекстов, каждый длиной VectorSize+1.
+type Model struct {
+	VocabSize  int
+	VectorSize int
+	Iteration  int         // число завершённых итераций
+	Source     TrainSource // на чём обучается модель
+	W          []float64
+	GradSq     []float64
+}
+
+// NewModel инициализирует параметры случайными значениями из
+// (-0.5, 0.5)/VectorSize, суммы градиентов — единицами.
+func NewModel(vocabSize, vectorSize int, seed int64) *Model {
+	size := 2 * vocabSize * (vectorSize + 1)
+	m := &Model{
+		VocabSize:  vocabSize,
+		VectorSize: vectorSize,
+		W:          make([]float64, size),
+		GradSq:     make([]float64, size),
+	}
+	rng := rand.New(rand.NewSource(seed))
+	for i := range m.W {
+		m.W[i] = (rng.Float64() - 0.5) / float64(vectorSize)
+		m.GradSq[i] = 1.0
+	}
+	return m
+}
+
+// CheckResume проверяет, что модель из контрольной точки можно обучать
+// дальше со словарём из vocabSize слов, размерностью vectorSize и
+// источником source.
+func (m *Model) CheckResume(vocabSize, vectorSize int, source TrainSource) error {
+	if m.VocabSize != vocabSize || m.VectorSize != vectorSize {
+		return fmt.Errorf("checkpoint has %d words and vector size %d, expected %d and %d",
+			m.VocabSize, m.VectorSize, vocabSize, vectorSize)
+	}
+	if changed := m.Source.diff(source); len(changed) > 0 {
+		return fmt.Errorf("checkpoint was trained differently: %s; start over without --resume",
+			strings.Join(changed, ", "))
+	}
+	return nil
+}
+
+// Train обучает модель на перемешанном файле записей CREC, продолжая
+// с итерации m.Iteration. Файл делится на Threads равных частей, каждая
+// обрабатывается своей горутиной без блокировок (Hogwild!), как в glove.c.
+// После каждой итерации вызывается report со средней стоимостью.
+func Train(m *Model, path string, opts TrainOptions, report func(iter int, cost float64)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to open cooccurrence file: %v", err)
+	}
+	numLines := info.Size() / RecordSize
+	if numLines == 0 {
+		return fmt.Errorf("cooccurrence file %s is empty", path)
+	}
+	if m.Source, err = NewTrainSource(path, opts); err != nil {
+		return err
+	}
+	if opts.Threads <= 0 {
+		opts.Threads = 1
+	}
+	perThread := numLines / int64(opts.Threads)
+
+	for m.Iteration < opts.Iterations {
+		costs := make([]float64, opts.Threads)
+		errs := make([]error, opts.Threads)
+		var wg sync.WaitGroup
+		for id := 0; id < opts.Threads; id++ {
+			lines := perThread
+			if id == opts.Threads-1 {
+				lines = numLines - perThread*int64(opts.Threads-1)
+			}
+			wg.Add(1)
+			go func(id int, lines int64) {
+				defer wg.Done()
+				costs[id], errs[id] = m.trainPart(path, int64(id)*perThread, lines, opts)
+			}(id, lines)
+		}
+		wg.Wait()
+
+		var cost float64
+		for id := range costs {
+			if errs[id] != nil {
+				return errs[id]
+			}
+			cost += costs[id]
+		}
+		m.Iteration++
+		if report != nil {
+			report(m.Iteration, cost/float64(numLines))
+		}
+
+		if opts.Checkpoint != "" && opts.CheckpointEvery > 0 &&
+			(m.Iteration%opts.CheckpointEvery == 0 || m.Iteration == opts.Iterations) {
+			if err := m.SaveCheckpoint(opts.Checkpoint); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// trainPart выполняет одну итерацию AdaGrad над lines записями,
+// начиная с записи offset, и возвращает их суммарную стоимость.
+func (m *Model) trainPart(path string, offset, lines int64, opts TrainOptions) (float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open cooccurrence file: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.Seek(offset*RecordSize, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("failed to seek cooccurrence file: %v", err)
+	}
+	reader := NewRecordReader(file)
+
+	size := m.VectorSize
+	W, gradsq := m.W, m.GradSq
+	updates1 := make([]float64, size)
+	updates2 := make([]float64, size)
+	var cost float64
+
+	for a := int64(0); a < lines; a++ {
+		cr, err := reader.Read()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read cooccurrence file: %v", err)
+		}
+		if cr.Word1 < 1 || cr.Word2 < 1 || int(cr.Word1) > m.VocabSize || int(cr.Word2) > m.VocabSize {
+			continue
+		}
+
+		l1 := (int(cr.Word1) - 1) * (size + 1)
+		l2 := (int(cr.Word2) - 1 + m.VocabSize) * (size + 1)
+
+		diff := 0.0
+		for b := 0; b < size; b++ {
+			diff += W[b+l1] * W[b+l2]
+		}
+		diff += W[size+l1] + W[size+l2] - math.Log(cr.Val)
+		fdiff := diff
+		if cr.Val <= opts.XMax {
+			fdiff = math.Pow(cr.Val/opts.XMax, opts.Alpha) * diff
+		}
+		if !isFinite(diff) || !isFinite(fdiff) {
+			continue
+		}
+		cost += 0.5 * fdiff * diff
+		// Как в glove.c: шаг обучения входит в градиент и векторов, и смещений
+		fdiff *= opts.Eta
+
+		// Адаптивные шаги для векторов
+		sum1, sum2 := 0.0, 0.0
+		for b := 0; b < size; b++ {
+			temp1 := clip(fdiff*W[b+l2], opts.GradClip)
+			temp2 := clip(fdiff*W[b+l1], opts.GradClip)
+			updates1[b] = temp1 / math.Sqrt(gradsq[b+l1])
+			updates2[b] = temp2 / math.Sqrt(gradsq[b+l2])
+			sum1 += updates1[b]
+			sum2 += updates2[b]
+			gradsq[b+l1] += temp1 * temp1
+			gradsq[b+l2] += temp2 * temp2
+		}
+		if isFinite(sum1) && isFinite(sum2) {
+			for b := 0; b < size; b++ {
+				W[b+l1] -= updates1[b]
+				W[b+l2] -= updates2[b]
+			}
+		}
+
+		// Смещения
+		W[size+l1] -= finiteOrZero(fdiff / math.Sqrt(gradsq[size+l1]))
+		W[size+l2] -= finiteOrZero(fdiff / math.Sqrt(gradsq[size+l2]))
+		fdiff *= fdiff
+		gradsq[size+l1] += fdiff
+		gradsq[size+l2] += fdiff
+	}
+	return cost, nil
+}
+
+func isFinite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+func finiteOrZero(x float64) float64 {
+	if !isFinite(x) {
+		return 0
+	}
+	return x
+}
+
+func clip(x, limit float64) float64 {
+	return math.Min(math.Max(x, -limit), limit)
+}
+
+// Output models, как параметр -model в glove.c.
+const (
+	ModelAll         = 0 // векторы слов и контекстов со смещениями
+	ModelWord        = 1 // только векторы слов
+	ModelWordContext = 2 // сумма векторов слова и контекста
+)
+
+// unknownWord — токен, для которого GloVe добавляет усреднённый вектор.
+const unknownWord = "<unk>"
+
+// WriteText пишет векторы в текстовом формате GloVe: слово и компоненты
+// с шестью знаками после запятой. Если в словаре нет <unk>, в конце
+// добавляется вектор <unk> — среднее по 100 самым редким словам.
+func (m *Model) WriteText(w io.Writer, words []string, model int, header bool) error {
+	if len(words) != m.VocabSize {
+		return fmt.Errorf("vocabulary has %d words, model has %d", len(words), m.VocabSize)
+	}
+	size := m.VectorSize
+	stride := size + 1
+	word := func(a int) []float64 { return m.W[a*stride : (a+1)*stride] }
+	context := func(a int) []float64 { return m.W[(m.VocabSize+a)*stride : (m.VocabSize+a+1)*stride] }
+
+	hasUnknown := false
+	for _, word := range words {
+		if word == unknownWord {
+			hasUnknown = true
+			break
+		}
+	}
+
+	bw := bufio.NewWriterSize(w, 1024*1024)
+	if header {
+		columns := size
+		if model == ModelAll {
+			columns = 2 * stride
+		}
+		rows := len(words)
+		if !hasUnknown {
+			rows++
+		}
+		fmt.Fprintf(bw, "%d %d\n", rows, columns)
+	}
+
+	buf := make([]byte, 0, 32)
+	writeVector := func(name string, wv, cv []float64) {
+		bw.WriteString(name)
+		switch model {
+		case ModelAll:
+			for _, v := range wv[:stride] {
+				bw.WriteByte(' ')
+				bw.Write(strconv.AppendFloat(buf[:0], v, 'f', 6, 64))
+			}
+			for _, v := range cv[:stride] {
+				bw.WriteByte(' ')
+				bw.Write(strconv.AppendFloat(buf[:0], v, 'f', 6, 64))
+			}
+		case ModelWord:
+			for _, v := range wv[:size] {
+				bw.WriteByte(' ')
+				bw.Write(strconv.AppendFloat(buf[:0], v, 'f', 6, 64))
+			}
+		default:
+			for b := 0; b < size; b++ {
+				bw.WriteByte(' ')
+				bw.Write(strconv.AppendFloat(buf[:0], wv[b]+cv[b], 'f', 6, 64))
+			}
+		}
+		bw.WriteByte('\n')
+	}
+
+	for a, name := range words {
+		writeVector(name, word(a), context(a))
+	}
+
+	if !hasUnknown {
+		rare := min(100, len(words))
+		unkWord := make([]float64, stride)
+		unkContext := make([]float64, stride)
+		for a := len(words) - rare; a < len(words); a++ {
+			for b := 0; b < stride; b++ {
+				unkWord[b] += word(a)[b] / float64(rare)
+				unkContext[b] += context(a)[b] / float64(rare)
+			}
+		}
+		writeVector(unknownWord, unkWord, unkContext)
+	}
+	return bw.Flush()
+}
+
+// WriteBinary пишет все параметры W подряд как float64 (little-endian),
+// как бинарный вывод glove.c.
+func (m *Model) WriteBinary(w io.Writer) error {
+	return writeFloats(w, m.W)
+}
+
+// checkpointMagic открывает файл контрольной точки.
+const checkpointMagic = "T2GCKPT2"
+
+// SaveCheckpoint атомарно сохраняет параметры, число итераций и
+// источник обучения.
+func (m *Model) SaveCheckpoint(path string) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %v", err)
+	}
+
+	bw := bufio.NewWriterSize(file, 1024*1024)
+	bw.WriteString(checkpointMagic)
+	for _, v := range []int64{int64(m.VocabSize), int64(m.VectorSize), int64(m.Iteration), int64(len(m.Source.Input))} {
+		binary.Write(bw, binary.LittleEndian, v)
+	}
+	bw.WriteString(m.Source.Input)
+	binary.Write(bw, binary.LittleEndian, m.Source.InputSize)
+	err = writeFloats(bw, []float64{m.Source.Eta, m.Source.Alpha, m.Source.XMax})
+	if err == nil {
+		err = writeFloats(bw, m.W)
+	}
+	if err == nil {
+		err = writeFloats(bw, m.GradSq)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	file.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %v", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint читает контрольную точку, сохранённую SaveCheckpoint.
+func LoadCheckpoint(path string) (*Model, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint: %v", err)
+	}
+	defer file.Close()
+
+	br := bufio.NewReaderSize(file, 1024*1024)
+	magic := make([]byte, len(checkpointMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != checkpointMagic {
+		return nil, fmt.Errorf("%s is not a text2glove checkpoint", path)
+	}
+	var header [4]int64
+	if err := binary.Read(br, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	m := &Model{VocabSize: int(header[0]), VectorSize: int(header[1]), Iteration: int(header[2])}
+	input := make([]byte, header[3])
+	if _, err := io.ReadFull(br, input); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	m.Source.Input = string(input)
+	if err := binary.Read(br, binary.LittleEndian, &m.Source.InputSize); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	params := make([]float64, 3)
+	if err := readFloats(br, params); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	m.Source.Eta, m.Source.Alpha, m.Source.XMax = params[0], params[1], params[2]
+	size := 2 * m.VocabSize * (m.VectorSize + 1)
+	m.W = make([]float64, size)
+	m.GradSq = make([]float64, size)
+	if err := readFloats(br, m.W); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	if err := readFloats(br, m.GradSq); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	return m, nil
+}
+
+func writeFloats(w io.Writer, values []float64) error {
+	bw := bufio.NewWriterSize(w, 1024*1024)
+	var buf [8]byte
+	for _, v := range values {
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		if _, err := bw.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func readFloats(r io.Reader, values []float64) error {
+	var buf [8]byte
+	for i := range values {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+		values[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[:]))
+	}
+	return nil
+}
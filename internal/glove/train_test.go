@@ -0,0 +1,136 @@
+package glove
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writePairs пишет все пары небольшого словаря с детерминированными
+// значениями совместной встречаемости.
+func writePairs(t *testing.T, path string, vocabSize int) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	w := NewRecordWriter(file)
+	for i := 1; i <= vocabSize; i++ {
+		for j := 1; j <= vocabSize; j++ {
+			if i == j {
+				continue
+			}
+			if err := w.Write(Record{Word1: int32(i), Word2: int32(j), Val: float64(1 + (i*j)%7)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func trainOptions(iterations int) TrainOptions {
+	return TrainOptions{Threads: 1, Iterations: iterations, Eta: 0.05, Alpha: 0.75, XMax: 100, GradClip: 100}
+}
+
+// В одном потоке обучение детерминировано: стоимость убывает, а
+// продолжение с контрольной точки даёт те же параметры, что и обучение
+// без перерыва.
+func TestTrainResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "cooccurrence.shuf.bin")
+	writePairs(t, input, 8)
+
+	var costs []float64
+	full := NewModel(8, 5, 1)
+	if err := Train(full, input, trainOptions(20), func(iter int, cost float64) {
+		costs = append(costs, cost)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(costs) != 20 {
+		t.Fatalf("report called %d times, want 20", len(costs))
+	}
+	for i := 1; i < len(costs); i++ {
+		if costs[i] >= costs[i-1] {
+			t.Fatalf("cost did not decrease at iteration %d: %v", i+1, costs)
+		}
+	}
+
+	checkpoint := filepath.Join(dir, "vectors.ckpt")
+	opts := trainOptions(10)
+	opts.Checkpoint = checkpoint
+	opts.CheckpointEvery = 5
+	part := NewModel(8, 5, 1)
+	if err := Train(part, input, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := LoadCheckpoint(checkpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resumed, part) {
+		t.Fatalf("checkpoint does not round-trip: iteration %d, source %+v; want %d, %+v",
+			resumed.Iteration, resumed.Source, part.Iteration, part.Source)
+	}
+
+	opts.Iterations = 20
+	source, err := NewTrainSource(input, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := resumed.CheckResume(8, 5, source); err != nil {
+		t.Fatal(err)
+	}
+	if err := Train(resumed, input, opts, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resumed.W, full.W) || !reflect.DeepEqual(resumed.GradSq, full.GradSq) {
+		t.Fatal("resumed training differs from uninterrupted training")
+	}
+}
+
+func TestCheckResumeRejectsChanges(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "cooccurrence.shuf.bin")
+	writePairs(t, input, 4)
+	other := filepath.Join(dir, "other.shuf.bin")
+	writePairs(t, other, 4)
+
+	m := NewModel(4, 3, 1)
+	if err := Train(m, input, trainOptions(1), nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		input  string
+		vocab  int
+		vector int
+		modify func(*TrainOptions)
+	}{
+		{"vocab size", input, 5, 3, nil},
+		{"vector size", input, 4, 4, nil},
+		{"input", other, 4, 3, nil},
+		{"eta", input, 4, 3, func(o *TrainOptions) { o.Eta = 0.1 }},
+		{"alpha", input, 4, 3, func(o *TrainOptions) { o.Alpha = 0.5 }},
+		{"x_max", input, 4, 3, func(o *TrainOptions) { o.XMax = 10 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := trainOptions(2)
+			if tt.modify != nil {
+				tt.modify(&opts)
+			}
+			source, err := NewTrainSource(tt.input, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := m.CheckResume(tt.vocab, tt.vector, source); err == nil {
+				t.Fatal("CheckResume accepted a changed setting")
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package hyphenation
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	softHyphen = '\u00AD'
+	shardCount = 64
+
+	// DefaultMaxWords ограничивает размер таблицы частот
+	DefaultMaxWords = 1 << 20
+)
+
+// compoundParts — части слов, которые пишутся через дефис и при переносе
+// должны сохранить его, даже если слово ещё не встречалось в корпусе.
+var compoundParts = map[string]bool{
+	"то": true, "либо": true, "нибудь": true, "ка": true, "таки": true, "де": true,
+}
+
+var compoundPrefixes = map[string]bool{
+	"кое": true, "кой": true,
+}
+
+// counts — таблица частот слов.
+type counts interface {
+	get(word string) uint32
+	add(word string)
+}
+
+type shard struct {
+	mu    sync.RWMutex
+	words map[string]uint32
+}
+
+// corpusCounts — общая таблица частот корпуса, разбитая на сегменты с
+// блокировками, чтобы рабочие меньше ждали друг друга.
+type corpusCounts struct {
+	shards      [shardCount]shard
+	maxPerShard int
+}
+
+func newCorpusCounts(maxWords int) *corpusCounts {
+	c := &corpusCounts{maxPerShard: maxWords/shardCount + 1}
+	for i := range c.shards {
+		c.shards[i].words = make(map[string]uint32)
+	}
+	return c
+}
+
+func (c *corpusCounts) get(word string) uint32 {
+	s := c.shard(word)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.words[word]
+}
+
+func (c *corpusCounts) add(word string) {
+	s := c.shard(word)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	increment(s.words, word, c.maxPerShard)
+}
+
+func (c *corpusCounts) shard(word string) *shard {
+	h := fnv.New32a()
+	h.Write([]byte(word))
+	return &c.shards[h.Sum32()%shardCount]
+}
+
+// documentCounts — частоты слов одного документа: документ обрабатывает
+// одна горутина, поэтому блокировки и сегменты не нужны.
+type documentCounts struct {
+	words    map[string]uint32
+	maxWords int
+}
+
+func (c *documentCounts) get(word string) uint32 {
+	return c.words[word]
+}
+
+func (c *documentCounts) add(word string) {
+	increment(c.words, word, c.maxWords)
+}
+
+// increment увеличивает частоту слова; новые слова не добавляются, если
+// в таблице уже limit слов.
+func increment(words map[string]uint32, word string, limit int) {
+	if n, ok := words[word]; ok {
+		if n < ^uint32(0) {
+			words[word] = n + 1
+		}
+	} else if len(words) < limit {
+		words[word] = 1
+	}
+}
+
+// Joiner склеивает слова, разорванные переносом в конце строки.
+// Решение о склейке принимается по частотам слов, уже встреченных
+// в корпусе (или, в режиме perDocument, в том же документе), и по
+// словарю. Joiner с частотами корпуса безопасен для использования из
+// нескольких горутин; Joiner документа (ForDocument) — нет.
+type Joiner struct {
+	dictionary  map[string]struct{}
+	counts      counts
+	maxWords    int
+	perDocument bool
+}
+
+// New создаёт Joiner. dictionary может быть nil; maxWords ограничивает
+// число различных слов в таблице частот (0 — DefaultMaxWords). При
+// perDocument частоты набираются отдельно для каждого документа (см.
+// ForDocument): решения тогда не зависят от порядка обработки файлов.
+func New(dictionary map[string]struct{}, maxWords int, perDocument bool) *Joiner {
+	if maxWords <= 0 {
+		maxWords = DefaultMaxWords
+	}
+	j := &Joiner{
+		dictionary:  dictionary,
+		maxWords:    maxWords,
+		perDocument: perDocument,
+	}
+	if perDocument {
+		j.counts = &documentCounts{words: make(map[string]uint32), maxWords: maxWords}
+	} else {
+		j.counts = newCorpusCounts(maxWords)
+	}
+	return j
+}
+
+// ForDocument возвращает Joiner для обработки одного документа: общий,
+// если частоты набираются по всему корпусу, или в режиме perDocument
+// новый, с тем же словарём и пустой таблицей частот документа.
+func (j *Joiner) ForDocument() *Joiner {
+	if !j.perDocument {
+		return j
+	}
+	return &Joiner{
+		dictionary: j.dictionary,
+		counts:     &documentCounts{words: make(map[string]uint32), maxWords: j.maxWords},
+		maxWords:   j.maxWords,
+	}
+}
+
+// LoadDictionary читает словарь: по одному слову на строку.
+func LoadDictionary(path string) (map[string]struct{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dictionary: %v", err)
+	}
+	defer file.Close()
+
+	dict := make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word != "" && !strings.HasPrefix(word, "#") {
+			dict[word] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %v", err)
+	}
+	return dict, nil
+}
+
+// Join присоединяет к началу line фрагмент слова head, перенесённый
+// с предыдущей строки, и отделяет висящий перенос в конце строки.
+// Возвращает текст строки и новый висящий фрагмент (с дефисом на конце),
+// который нужно передать в следующий вызов. Мягкие переносы удаляются.
+func (j *Joiner) Join(head, line string) (text, tail string) {
+	if head != "" {
+		line = j.attach(head, line)
+	}
+
+	text, tail = splitTail(line)
+	text = removeSoftHyphens(text)
+	j.observe(text)
+	return text, tail
+}
+
+// attach склеивает висящий фрагмент с первым словом строки.
+func (j *Joiner) attach(head, line string) string {
+	trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+	first, _ := utf8.DecodeRuneInString(trimmed)
+	if !unicode.IsLower(first) {
+		// Следующая строка не продолжает слово: перенос был настоящим дефисом
+		return head + " " + line
+	}
+
+	end := strings.IndexFunc(trimmed, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(trimmed)
+	}
+	continuation, rest := trimmed[:end], trimmed[end:]
+
+	hyphen, _ := utf8.DecodeLastRuneInString(head)
+	prefix := head[:len(head)-utf8.RuneLen(hyphen)]
+	if hyphen == softHyphen {
+		return removeSoftHyphens(prefix) + continuation + rest
+	}
+
+	// prefix может начинаться с пунктуации: «(пере-» → слово «пере»
+	start := strings.LastIndexFunc(prefix, func(r rune) bool { return !unicode.IsLetter(r) && !isHyphen(r) })
+	word := prefix[start+1:]
+	if j.keepHyphen(word, continuation) {
+		return prefix + "-" + continuation + rest
+	}
+	return prefix + continuation + rest
+}
+
+// keepHyphen решает, является ли дефис в конце строки частью составного слова.
+func (j *Joiner) keepHyphen(left, right string) bool {
+	left, right = strings.ToLower(left), strings.ToLower(right)
+	joined := j.frequency(left + right)
+	hyphenated := j.frequency(left + "-" + right)
+
+	switch {
+	case hyphenated > joined:
+		return true
+	case joined > 0:
+		return false
+	}
+	// Слово не встречалось ни в одной форме: опираемся на типичные составные части
+	if compoundParts[right] || compoundPrefixes[left] {
+		return true
+	}
+	return strings.HasPrefix(left, "по") && (strings.HasSuffix(right, "ски") || strings.HasSuffix(right, "ому") || strings.HasSuffix(right, "ему"))
+}
+
+// frequency возвращает частоту слова; слова из словаря считаются встреченными.
+func (j *Joiner) frequency(word string) uint32 {
+	n := j.counts.get(word)
+	if _, ok := j.dictionary[word]; ok {
+		n++
+	}
+	return n
+}
+
+// observe учитывает слова строки в таблице частот.
+func (j *Joiner) observe(text string) {
+	for _, token := range strings.Fields(text) {
+		word := strings.ToLower(strings.TrimFunc(token, func(r rune) bool { return !unicode.IsLetter(r) }))
+		if word == "" {
+			continue
+		}
+		j.counts.add(word)
+	}
+}
+
+// splitTail отделяет от строки последнее слово, если оно оборвано переносом.
+func splitTail(line string) (text, tail string) {
+	trimmed := strings.TrimRightFunc(line, unicode.IsSpace)
+	last, size := utf8.DecodeLastRuneInString(trimmed)
+	if !isHyphen(last) && last != softHyphen {
+		return line, ""
+	}
+	beforeHyphen, _ := utf8.DecodeLastRuneInString(trimmed[:len(trimmed)-size])
+	if !unicode.IsLetter(beforeHyphen) {
+		return line, ""
+	}
+
+	start := strings.LastIndexFunc(trimmed, unicode.IsSpace) + 1
+	return trimmed[:start], trimmed[start:]
+}
+
+func isHyphen(r rune) bool {
+	switch r {
+	case '-', '\u2010', '\u2011', '\u00AC': // дефис, неразрывный дефис, знак переноса в OCR
+		return true
+	}
+	return false
+}
+
+func removeSoftHyphens(text string) string {
+	if !strings.ContainsRune(text, softHyphen) {
+		return text
+	}
+	return strings.ReplaceAll(text, string(softHyphen), "")
+}
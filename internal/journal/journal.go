@@ -0,0 +1,371 @@
+package journal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version — версия формата журнала.
+const Version = 2
+
+// tailSize — сколько последних байт вывода хешируется для проверки
+// при возобновлении.
+const tailSize = 64 * 1024
+
+// Journal — прогресс предобработки: сколько байт и строк вывода
+// записано и какие входные файлы им соответствуют. Снимок постоянного
+// размера пишется целиком через временный файл и переименование, а
+// обработанные файлы дописываются в отдельный список (FilesPath), длину
+// которого снимок запоминает. Поэтому на диске всегда лежит
+// согласованная версия, а сохранение не дорожает с числом файлов.
+type Journal struct {
+	Version  int            `json:"version"`
+	Output   string         `json:"output"`
+	Config   map[string]any `json:"config"`                    // настройки, влияющие на вывод
+	Offset   int64          `json:"offset"`                    // размер вывода на момент снимка
+	Lines    uint64         `json:"lines"`                     // строк вывода на момент снимка
+	TailHash string         `json:"tail_sha256"`               // sha256 последних байт вывода перед Offset
+	Files    int            `json:"files"`                     // обработанных файлов на момент снимка
+	FilesLog int64          `json:"files_size"`                // длина списка файлов на момент снимка
+	Metadata int64          `json:"metadata_offset,omitempty"` // размер файла метаданных на момент снимка
+	Updated  time.Time      `json:"updated"`
+
+	Interrupted bool `json:"interrupted,omitempty"` // запуск остановлен сигналом
+
+	completed map[string]bool // файлы из списка на момент снимка
+	pending   []string        // файлы, записанные после снимка
+}
+
+// New создаёт пустой журнал для вывода output.
+func New(output string, config map[string]any) *Journal {
+	return &Journal{Version: Version, Output: output, Config: config}
+}
+
+// DefaultPath возвращает путь журнала по умолчанию: рядом с выводом.
+func DefaultPath(output string) string {
+	return output + ".journal"
+}
+
+// FilesPath возвращает путь списка обработанных файлов журнала path.
+func FilesPath(path string) string {
+	return path + ".files"
+}
+
+// Load читает журнал и список обработанных файлов до длины, записанной
+// в снимке: файлы, дописанные после него, не учитываются.
+func Load(path string) (*Journal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %v", err)
+	}
+	var j Journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse journal %s: %v", path, err)
+	}
+	if j.Version != Version {
+		return nil, fmt.Errorf("journal %s has version %d, expected %d", path, j.Version, Version)
+	}
+	if j.completed, err = readFiles(FilesPath(path), j.FilesLog); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// Resume загружает журнал прерванного запуска и проверяет, что
+// настройки config, влияющие на вывод, с тех пор не изменились.
+func Resume(path string, config map[string]any) (*Journal, error) {
+	j, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if changed := Diff(j.Config, config); len(changed) > 0 {
+		return nil, fmt.Errorf("settings changed since the interrupted run: %s; start over without --resume",
+			strings.Join(changed, ", "))
+	}
+	return j, nil
+}
+
+// readFiles читает первые size байт списка обработанных файлов.
+func readFiles(path string, size int64) (map[string]bool, error) {
+	completed := make(map[string]bool)
+	if size == 0 {
+		return completed, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal files: %v", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(io.LimitReader(file, size))
+	var read int64
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		read += int64(len(line))
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read journal files: %v", err)
+		}
+		name, uerr := strconv.Unquote(strings.TrimSuffix(line, "\n"))
+		if uerr != nil {
+			return nil, fmt.Errorf("journal files %s is corrupted: %v", path, uerr)
+		}
+		completed[name] = true
+	}
+	if read < size {
+		return nil, fmt.Errorf("journal files %s has %d bytes, journal expects %d", path, read, size)
+	}
+	return completed, nil
+}
+
+// appendFiles дописывает файлы в список после первых size байт (всё, что
+// дальше, осталось от незавершённого сохранения) и возвращает новую длину.
+func appendFiles(path string, size int64, files []string) (int64, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open journal files: %v", err)
+	}
+	defer file.Close()
+	if err := file.Truncate(size); err != nil {
+		return 0, fmt.Errorf("failed to truncate journal files: %v", err)
+	}
+
+	var buf []byte
+	for _, name := range files {
+		buf = strconv.AppendQuote(buf, name)
+		buf = append(buf, '\n')
+	}
+	if _, err := file.WriteAt(buf, size); err != nil {
+		return 0, fmt.Errorf("failed to write journal files: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync journal files: %v", err)
+	}
+	return size + int64(len(buf)), nil
+}
+
+// Save дописывает файлы, отмеченные после прошлого снимка, в список и
+// атомарно записывает снимок: во временный файл рядом, fsync, затем
+// переименование поверх старой версии.
+func (j *Journal) Save(path string) error {
+	size, err := appendFiles(FilesPath(path), j.FilesLog, j.pending)
+	if err != nil {
+		return err
+	}
+	j.FilesLog = size
+	j.Files += len(j.pending)
+	j.pending = j.pending[:0]
+
+	j.Updated = time.Now().UTC()
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create journal: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync journal: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace journal: %v", err)
+	}
+	return nil
+}
+
+// Completed возвращает множество файлов, обработанных до загруженного
+// снимка.
+func (j *Journal) Completed() map[string]bool {
+	if j.completed == nil {
+		return map[string]bool{}
+	}
+	return j.completed
+}
+
+// Checkpoint фиксирует состояние вывода: файлы files записаны, вывод
+// сброшен на диск и имеет длину offset. output нужен для хеша хвоста.
+// На диск состояние попадает при Save.
+func (j *Journal) Checkpoint(output io.ReaderAt, offset int64, lines uint64, files []string) error {
+	hash, err := tailHash(output, offset)
+	if err != nil {
+		return fmt.Errorf("failed to hash output: %v", err)
+	}
+	j.pending = append(j.pending, files...)
+	j.Offset = offset
+	j.Lines = lines
+	j.TailHash = hash
+	return nil
+}
+
+// Recorder ведёт журнал во время записи вывода: копит записанные файлы
+// и сохраняет снимки не чаще чем раз в every.
+type Recorder struct {
+	journal *Journal
+	path    string
+	every   time.Duration
+	resume  bool
+	pending []string
+	last    time.Time
+}
+
+// NewRecorder создаёт Recorder для журнала j, сохраняемого в path. При
+// resume вывод дописывается после последнего снимка j.
+func NewRecorder(j *Journal, path string, every time.Duration, resume bool) *Recorder {
+	return &Recorder{journal: j, path: path, every: every, resume: resume, last: time.Now()}
+}
+
+// Resume возвращает длину и число строк вывода, после которых он
+// дописывается; ok == false — вывод пишется заново.
+func (r *Recorder) Resume() (offset int64, lines uint64, ok bool) {
+	return r.journal.Offset, r.journal.Lines, r.resume
+}
+
+// Written отмечает, что документ path записан. due сообщает, что пора
+// сохранить снимок.
+func (r *Recorder) Written(path string) (due bool) {
+	r.pending = append(r.pending, path)
+	return time.Since(r.last) >= r.every
+}
+
+// Checkpoint сохраняет снимок: вывод сброшен на диск и имеет длину
+// offset, файл метаданных — metadata байт; interrupted — запуск
+// остановлен сигналом.
+func (r *Recorder) Checkpoint(output io.ReaderAt, offset int64, lines uint64, metadata int64, interrupted bool) error {
+	r.journal.Metadata = metadata
+	r.journal.Interrupted = interrupted
+	if err := r.journal.Checkpoint(output, offset, lines, r.pending); err != nil {
+		return err
+	}
+	r.pending = r.pending[:0]
+	if err := r.journal.Save(r.path); err != nil {
+		return err
+	}
+	r.last = time.Now()
+	return nil
+}
+
+// Restore готовит вывод к дозаписи: проверяет, что файл не короче
+// журнала и его хвост совпадает с записанным, и обрезает строки,
+// записанные после последнего снимка.
+func (j *Journal) Restore(path string) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open output for resume: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() < j.Offset {
+		return fmt.Errorf("output %s has %d bytes, journal expects at least %d", path, info.Size(), j.Offset)
+	}
+	hash, err := tailHash(file, j.Offset)
+	if err != nil {
+		return fmt.Errorf("failed to hash output: %v", err)
+	}
+	if hash != j.TailHash {
+		return fmt.Errorf("output %s does not match the journal (was it modified?)", path)
+	}
+	if err := file.Truncate(j.Offset); err != nil {
+		return fmt.Errorf("failed to truncate output: %v", err)
+	}
+	return nil
+}
+
+// RestoreMetadata обрезает файл метаданных до размера на момент
+// последнего снимка: записи о документах, строки которых отброшены
+// Restore, удаляются вместе с ними.
+func (j *Journal) RestoreMetadata(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to open metadata for resume: %v", err)
+	}
+	if info.Size() < j.Metadata {
+		return fmt.Errorf("metadata %s has %d bytes, journal expects at least %d", path, info.Size(), j.Metadata)
+	}
+	if err := os.Truncate(path, j.Metadata); err != nil {
+		return fmt.Errorf("failed to truncate metadata: %v", err)
+	}
+	return nil
+}
+
+// tailHash считает sha256 последних tailSize байт перед offset.
+func tailHash(r io.ReaderAt, offset int64) (string, error) {
+	start := max(offset-tailSize, 0)
+	buf := make([]byte, offset-start)
+	if _, err := r.ReadAt(buf, start); err != nil && err != io.EOF {
+		return "", err
+	}
+	sum := sha256.Sum256(buf)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Fingerprint переводит настройки в вид, пригодный для сохранения в
+// журнале и сравнения после загрузки (через JSON).
+func Fingerprint(config any) (map[string]any, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Diff возвращает имена настроек, которые различаются в a и b
+// (вложенные — через точку).
+func Diff(a, b map[string]any) []string {
+	var changed []string
+	diff("", a, b, &changed)
+	sort.Strings(changed)
+	return changed
+}
+
+func diff(prefix string, a, b map[string]any, changed *[]string) {
+	keys := make(map[string]bool)
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	for k := range keys {
+		am, aok := a[k].(map[string]any)
+		bm, bok := b[k].(map[string]any)
+		if aok && bok {
+			diff(prefix+k+".", am, bm, changed)
+			continue
+		}
+		if !reflect.DeepEqual(a[k], b[k]) {
+			*changed = append(*changed, prefix+k)
+		}
+	}
+}
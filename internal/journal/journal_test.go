@@ -0,0 +1,269 @@
+package journal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Хеш хвоста берётся по последним tailSize байтам перед offset, байты
+// после offset не учитываются.
+func TestTailHash(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), tailSize/8)
+	sum := func(b []byte) string {
+		s := sha256.Sum256(b)
+		return hex.EncodeToString(s[:])
+	}
+	tests := []struct {
+		name   string
+		offset int64
+		want   string
+	}{
+		{"empty", 0, sum(nil)},
+		{"short", 10, sum(data[:10])},
+		{"exactly tail", tailSize, sum(data[:tailSize])},
+		{"long", int64(len(data)) - 3, sum(data[len(data)-3-tailSize : len(data)-3])},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tailHash(bytes.NewReader(data), tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("tailHash(%d) = %s, want %s", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a, err := Fingerprint(map[string]any{
+		"mode":   "modern",
+		"filter": map[string]any{"min_length": 2, "stopwords": []string{"ru"}},
+		"sample": 0.001,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Fingerprint(map[string]any{
+		"mode":   "modern",
+		"filter": map[string]any{"min_length": 3, "stopwords": []string{"ru"}},
+		"seed":   1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"filter.min_length", "sample", "seed"}
+	if got := Diff(a, b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff = %v, want %v", got, want)
+	}
+	if got := Diff(a, a); len(got) != 0 {
+		t.Fatalf("Diff of equal configs = %v", got)
+	}
+}
+
+// run имитирует запись вывода: документ — строка в выводе и запись в
+// метаданных, снимки сохраняются явно через checkpoint.
+type run struct {
+	t        *testing.T
+	recorder *Recorder
+	output   *os.File
+	metadata *os.File
+}
+
+func startRun(t *testing.T, j *Journal, dir string, resume bool) *run {
+	t.Helper()
+	output, err := os.OpenFile(filepath.Join(dir, "out.txt"), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := os.OpenFile(filepath.Join(dir, "out.meta"), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		output.Close()
+		metadata.Close()
+	})
+	return &run{t: t, recorder: NewRecorder(j, filepath.Join(dir, "out.journal"), time.Hour, resume), output: output, metadata: metadata}
+}
+
+func (r *run) write(source string) {
+	r.t.Helper()
+	line := strings.ReplaceAll(source, "\n", " ")
+	if _, err := r.output.WriteString("line of " + line + "\n"); err != nil {
+		r.t.Fatal(err)
+	}
+	if _, err := r.metadata.WriteString(line + "\n"); err != nil {
+		r.t.Fatal(err)
+	}
+	r.recorder.Written(source)
+}
+
+func (r *run) checkpoint() {
+	r.t.Helper()
+	offset, err := r.output.Seek(0, 2)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	metadata, err := r.metadata.Seek(0, 2)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	lines := uint64(bytes.Count(mustRead(r.t, r.output.Name()), []byte("\n")))
+	if err := r.recorder.Checkpoint(r.output, offset, lines, metadata, false); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// Прерванный запуск: строки после последнего снимка обрезаются в выводе
+// и метаданных, в список обработанных попадают только файлы до снимка,
+// а продолжение дописывается к ним.
+func TestResumeTruncatesAndContinues(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.journal")
+	config := map[string]any{"mode": "modern"}
+
+	first := startRun(t, New(filepath.Join(dir, "out.txt"), config), dir, false)
+	first.write("a.gz")
+	first.write("b.gz")
+	first.checkpoint()
+	first.write("c.gz")
+	first.checkpoint()
+	// Сбой после записи d.gz, до следующего снимка
+	first.write("d.gz")
+
+	j, err := Resume(path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Restore(filepath.Join(dir, "out.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.RestoreMetadata(filepath.Join(dir, "out.meta")); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"a.gz": true, "b.gz": true, "c.gz": true}
+	if got := j.Completed(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Completed = %v, want %v", got, want)
+	}
+	if j.Files != 3 || j.Lines != 3 {
+		t.Fatalf("journal has %d files and %d lines, want 3 and 3", j.Files, j.Lines)
+	}
+	if got := string(mustRead(t, filepath.Join(dir, "out.txt"))); got != "line of a.gz\nline of b.gz\nline of c.gz\n" {
+		t.Fatalf("output after restore = %q", got)
+	}
+	if got := string(mustRead(t, filepath.Join(dir, "out.meta"))); got != "a.gz\nb.gz\nc.gz\n" {
+		t.Fatalf("metadata after restore = %q", got)
+	}
+
+	second := startRun(t, j, dir, true)
+	second.write("d.gz")
+	second.write("e\n\"quoted\".gz")
+	second.checkpoint()
+
+	j, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want["d.gz"] = true
+	want["e\n\"quoted\".gz"] = true
+	if got := j.Completed(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Completed after resume = %v, want %v", got, want)
+	}
+	if j.Files != 5 || j.Lines != 5 {
+		t.Fatalf("journal has %d files and %d lines, want 5 and 5", j.Files, j.Lines)
+	}
+}
+
+// Список файлов, дописанный без сохранения снимка (сбой между ними),
+// при загрузке не учитывается и перезаписывается следующим снимком.
+func TestLoadIgnoresFilesAfterSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.journal")
+
+	r := startRun(t, New(filepath.Join(dir, "out.txt"), nil), dir, false)
+	r.write("a.gz")
+	r.checkpoint()
+	if _, err := appendFiles(FilesPath(path), r.recorder.journal.FilesLog, []string{"lost.gz"}); err != nil {
+		t.Fatal(err)
+	}
+
+	j, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := j.Completed(); !reflect.DeepEqual(got, map[string]bool{"a.gz": true}) {
+		t.Fatalf("Completed = %v", got)
+	}
+
+	r = startRun(t, j, dir, true)
+	r.write("b.gz")
+	r.checkpoint()
+	if got := string(mustRead(t, FilesPath(path))); got != "\"a.gz\"\n\"b.gz\"\n" {
+		t.Fatalf("files list = %q", got)
+	}
+}
+
+func TestResumeRejectsChangedConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.journal")
+	saved, _ := Fingerprint(map[string]any{"mode": "modern", "filter": map[string]any{"min_length": 2}})
+	if err := New(filepath.Join(dir, "out.txt"), saved).Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Resume(path, saved); err != nil {
+		t.Fatalf("unchanged config rejected: %v", err)
+	}
+	changed, _ := Fingerprint(map[string]any{"mode": "all", "filter": map[string]any{"min_length": 2}})
+	_, err := Resume(path, changed)
+	if err == nil || !strings.Contains(err.Error(), "mode") {
+		t.Fatalf("changed config: err = %v, want an error naming mode", err)
+	}
+}
+
+func TestRestoreRejectsModifiedOutput(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "out.txt")
+	r := startRun(t, New(output, nil), dir, false)
+	r.write("a.gz")
+	r.write("b.gz")
+	r.checkpoint()
+	j, err := Load(filepath.Join(dir, "out.journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(output, []byte("line of a.gz\nline of X.gz\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Restore(output); err == nil {
+		t.Fatal("Restore accepted output with a different tail")
+	}
+	if err := os.WriteFile(output, []byte("line of a.gz\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Restore(output); err == nil {
+		t.Fatal("Restore accepted output shorter than the journal")
+	}
+	if err := j.RestoreMetadata(filepath.Join(dir, "missing.meta")); err == nil {
+		t.Fatal("RestoreMetadata accepted a missing file")
+	}
+}
@@ -2,78 +2,51 @@ package lemmatizer
 
 import (
 	"bytes"
+	"context"
 	"fmt"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"regexp"
 	"strings"
-	"sync"
-	"unicode/utf8"
+	"time"
+
+	"github.com/terratensor/text2glove/internal/cleaner"
 )
 
 const (
 	maxChunkSize = 5 * 1024 * 1024 // 5MB
 )
 
+// Lemmatizer лемматизирует текст внешней программой mystem.
+// Слишком длинные токены, на которых mystem может сбоить, должны быть
+// отброшены до вызова (см. пакет filter).
 type Lemmatizer struct {
 	mystemPath  string
 	mystemFlags []string
-	logFile     *os.File
-	logEnabled  bool
-	logMutex    sync.Mutex
 }
 
-func New(mystemPath, flags string, logEnabled bool, logPath string) (*Lemmatizer, error) {
-	lem := &Lemmatizer{
+func New(mystemPath, flags string) *Lemmatizer {
+	return &Lemmatizer{
 		mystemPath:  mystemPath,
 		mystemFlags: parseFlags(flags),
-		logEnabled:  logEnabled,
-	}
-
-	// Создаем лог-файл только если логирование включено
-	if logEnabled {
-		// Создаем директории, если их нет
-		if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
-			return nil, fmt.Errorf("failed to create log directory: %v", err)
-		}
-
-		file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open log file: %v", err)
-		}
-		lem.logFile = file
 	}
-	return lem, nil
 }
 
-func (l *Lemmatizer) Close() {
-	if l.logFile != nil {
-		l.logFile.Close()
-	}
-}
-
-func (l *Lemmatizer) Lemmatize(text, filename string) (string, error) {
+// Lemmatize лемматизирует текст. При отмене ctx запущенный mystem
+// завершается, а Lemmatize возвращает ошибку.
+func (l *Lemmatizer) Lemmatize(ctx context.Context, text string) (string, error) {
 	if text == "" {
 		return "", nil
 	}
 
-	// Фильтрация токенов
-	var validTokens []string
-	tokens := strings.Fields(text)
-	for _, token := range tokens {
-		tokenLen := utf8.RuneCountInString(token)
-
-		switch {
-		case tokenLen > 100: // Опасные токены
-			l.logToken(filename, token, "DANGER")
-			continue
-		case tokenLen > 50: // Длинные токены
-			l.logToken(filename, token, "LONG")
+	if hasPlaceholders(text) {
+		lines, err := l.LemmatizeLines(ctx, []string{text})
+		if err != nil {
+			return "", err
 		}
-		validTokens = append(validTokens, token)
+		return lines[0], nil
 	}
 
+	validTokens := strings.Fields(text)
 	if len(validTokens) == 0 {
 		return "", nil
 	}
@@ -89,7 +62,7 @@ func (l *Lemmatizer) Lemmatize(text, filename string) (string, error) {
 
 		// Если добавление токена превысит лимит, обрабатываем текущую порцию
 		if currentSize+tokenSize > maxChunkSize && currentSize > 0 {
-			if chunkResult, err := l.processChunk(currentChunk.String()); err == nil {
+			if chunkResult, err := l.processChunk(ctx, currentChunk.String()); err == nil {
 				resultBuilder.WriteString(chunkResult)
 				resultBuilder.WriteString(" ")
 			} else {
@@ -107,7 +80,7 @@ func (l *Lemmatizer) Lemmatize(text, filename string) (string, error) {
 
 	// Обрабатываем последнюю порцию
 	if currentSize > 0 {
-		if chunkResult, err := l.processChunk(currentChunk.String()); err == nil {
+		if chunkResult, err := l.processChunk(ctx, currentChunk.String()); err == nil {
 			resultBuilder.WriteString(chunkResult)
 		} else {
 			return "", fmt.Errorf("chunk processing failed: %v", err)
@@ -117,31 +90,149 @@ func (l *Lemmatizer) Lemmatize(text, filename string) (string, error) {
 	return resultBuilder.String(), nil
 }
 
-func (l *Lemmatizer) processChunk(chunk string) (string, error) {
-	cmd := exec.Command(l.mystemPath, append(l.mystemFlags, "-")...)
-	cmd.Stdin = strings.NewReader(chunk)
+// LemmatizeLines лемматизирует строки (например, предложения), сохраняя
+// их границы: i-я строка результата соответствует i-й входной.
+func (l *Lemmatizer) LemmatizeLines(ctx context.Context, lines []string) ([]string, error) {
+	// Токены-заменители (<NUM>) не передаются в mystem: строка делится
+	// на участки между ними, а после лемматизации собирается обратно
+	var segments []string
+	placeholders := make([][]string, len(lines))
+	for i, line := range lines {
+		parts, marks := splitPlaceholders(line)
+		segments = append(segments, parts...)
+		placeholders[i] = marks
+	}
 
-	var out, stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
+	lemmatized, err := l.lemmatizeSegments(ctx, segments)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("mystem error: %v, stderr: %s", err, stderr.String())
+	result := make([]string, len(lines))
+	pos := 0
+	for i, marks := range placeholders {
+		parts := []string{lemmatized[pos]}
+		pos++
+		for _, mark := range marks {
+			parts = append(parts, mark, lemmatized[pos])
+			pos++
+		}
+		result[i] = strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+	}
+	return result, nil
+}
+
+func (l *Lemmatizer) lemmatizeSegments(ctx context.Context, lines []string) ([]string, error) {
+	result := make([]string, 0, len(lines))
+	var chunk []string
+	chunkSize := 0
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		lemmatized, err := l.processLines(ctx, chunk)
+		if err != nil {
+			return fmt.Errorf("chunk processing failed: %v", err)
+		}
+		result = append(result, lemmatized...)
+		chunk = chunk[:0]
+		chunkSize = 0
+		return nil
+	}
+
+	for _, line := range lines {
+		filtered := strings.Join(strings.Fields(line), " ")
+		if chunkSize+len(filtered)+1 > maxChunkSize && chunkSize > 0 {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		chunk = append(chunk, filtered)
+		chunkSize += len(filtered) + 1
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// hasPlaceholders сообщает, есть ли в тексте токены-заменители вида <NUM>.
+func hasPlaceholders(text string) bool {
+	if !strings.Contains(text, "<") {
+		return false
+	}
+	for _, token := range strings.Fields(text) {
+		if cleaner.IsPlaceholder(token) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitPlaceholders делит строку на участки между токенами-заменителями;
+// участков всегда на один больше, чем заменителей.
+func splitPlaceholders(line string) (segments, placeholders []string) {
+	var current []string
+	for _, token := range strings.Fields(line) {
+		if cleaner.IsPlaceholder(token) {
+			segments = append(segments, strings.Join(current, " "))
+			placeholders = append(placeholders, token)
+			current = current[:0]
+			continue
+		}
+		current = append(current, token)
 	}
+	return append(segments, strings.Join(current, " ")), placeholders
+}
 
-	return processMystemOutput(out.String()), nil
+func (l *Lemmatizer) processChunk(ctx context.Context, chunk string) (string, error) {
+	out, err := l.runMystem(ctx, l.mystemFlags, chunk)
+	if err != nil {
+		return "", err
+	}
+	return processMystemOutput(out), nil
 }
 
-func (l *Lemmatizer) logToken(filename, token, level string) {
-	if !l.logEnabled || l.logFile == nil {
-		return
+// processLines прогоняет строки через mystem с флагом -c (копировать весь ввод),
+// чтобы переводы строк сохранились в выводе.
+func (l *Lemmatizer) processLines(ctx context.Context, lines []string) ([]string, error) {
+	out, err := l.runMystem(ctx, append(l.mystemFlags[:len(l.mystemFlags):len(l.mystemFlags)], "-c"), strings.Join(lines, "\n")+"\n")
+	if err != nil {
+		return nil, err
+	}
+
+	outLines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(outLines) != len(lines) {
+		return nil, fmt.Errorf("mystem returned %d lines for %d input lines", len(outLines), len(lines))
 	}
+	for i, line := range outLines {
+		outLines[i] = processMystemOutput(line)
+	}
+	return outLines, nil
+}
 
-	l.logMutex.Lock()
-	defer l.logMutex.Unlock()
+func (l *Lemmatizer) runMystem(ctx context.Context, flags []string, input string) (string, error) {
+	// Копируем флаги: общий срез используется из нескольких горутин
+	args := append(append([]string{}, flags...), "-")
+	// При отмене ctx процесс mystem убивается, дочерних процессов не остаётся
+	cmd := exec.CommandContext(ctx, l.mystemPath, args...)
+	cmd.WaitDelay = time.Second
+	cmd.Stdin = strings.NewReader(input)
+
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("mystem error: %v, stderr: %s", err, stderr.String())
+	}
 
-	logLine := fmt.Sprintf("[%s] %s: %s\n", level, filename, token)
-	l.logFile.WriteString(logLine)
+	return out.String(), nil
 }
 
 func processMystemOutput(output string) string {
@@ -0,0 +1,224 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Состояния обработки для /status.
+const (
+	StateRunning     = "running"
+	StateCompleted   = "completed"
+	StateInterrupted = "interrupted"
+	StateFailed      = "failed"
+)
+
+// mystemBuckets — верхние границы корзин гистограммы задержки mystem, секунды.
+var mystemBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Output — показатели вывода, которые ведёт писатель.
+type Output struct {
+	Lines     uint64
+	Bytes     uint64
+	Corrupted uint64
+}
+
+// queue — канал, глубину которого показывают метрики.
+type queue struct {
+	length   func() int
+	capacity int
+}
+
+// Metrics собирает показатели долгого запуска для Prometheus и /status.
+// Счётчики рабочих обновляются атомарно; показатели писателя, трекера
+// ошибок и очередей читаются через функции в момент запроса. Методы
+// безопасны для nil: без --http метрики не собираются.
+type Metrics struct {
+	started time.Time
+	total   atomic.Int64
+
+	processed  atomic.Uint64
+	failed     atomic.Uint64
+	inputBytes atomic.Uint64
+	busy       []atomic.Int64 // наносекунды работы по рабочим
+
+	mystemMu     sync.Mutex
+	mystemCounts []uint64 // по корзинам mystemBuckets, последняя — +Inf
+	mystemSum    float64
+	mystemCount  uint64
+
+	mu     sync.Mutex
+	state  string
+	output func() Output
+	errors func() map[string]int
+	queues map[string]queue
+}
+
+// New создаёт метрики для workers рабочих (номера с 1).
+func New(workers int) *Metrics {
+	return &Metrics{
+		started:      time.Now(),
+		busy:         make([]atomic.Int64, workers),
+		mystemCounts: make([]uint64, len(mystemBuckets)+1),
+		state:        StateRunning,
+		queues:       make(map[string]queue),
+	}
+}
+
+// SetTotal задаёт число входных файлов этого запуска.
+func (m *Metrics) SetTotal(n int) {
+	if m == nil {
+		return
+	}
+	m.total.Store(int64(n))
+}
+
+// SetState задаёт состояние обработки.
+func (m *Metrics) SetState(state string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.state = state
+	m.mu.Unlock()
+}
+
+// SetOutput задаёт источник показателей вывода.
+func (m *Metrics) SetOutput(output func() Output) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.output = output
+	m.mu.Unlock()
+}
+
+// SetErrors задаёт источник числа ошибок по классам.
+func (m *Metrics) SetErrors(errors func() map[string]int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.errors = errors
+	m.mu.Unlock()
+}
+
+// AddQueue регистрирует очередь: length возвращает её текущую глубину.
+func (m *Metrics) AddQueue(name string, length func() int, capacity int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.queues[name] = queue{length: length, capacity: capacity}
+	m.mu.Unlock()
+}
+
+// FileProcessed учитывает обработанный файл и его распакованный размер.
+func (m *Metrics) FileProcessed(bytes int64) {
+	if m == nil {
+		return
+	}
+	m.processed.Add(1)
+	m.inputBytes.Add(uint64(bytes))
+}
+
+// FileFailed учитывает файл, который не удалось обработать.
+func (m *Metrics) FileFailed() {
+	if m == nil {
+		return
+	}
+	m.failed.Add(1)
+}
+
+// AddBusy добавляет рабочему worker время d, занятое обработкой файла.
+func (m *Metrics) AddBusy(worker int, d time.Duration) {
+	if m == nil || worker < 1 || worker > len(m.busy) {
+		return
+	}
+	m.busy[worker-1].Add(int64(d))
+}
+
+// ObserveMystem учитывает длительность одного вызова mystem.
+func (m *Metrics) ObserveMystem(d time.Duration) {
+	if m == nil {
+		return
+	}
+	seconds := d.Seconds()
+	i := sort.SearchFloat64s(mystemBuckets, seconds)
+	m.mystemMu.Lock()
+	m.mystemCounts[i]++
+	m.mystemSum += seconds
+	m.mystemCount++
+	m.mystemMu.Unlock()
+}
+
+// WriteText пишет метрики в текстовом формате Prometheus.
+func (m *Metrics) WriteText(w io.Writer) {
+	s := m.Status()
+
+	family(w, "text2glove_files", "gauge", "Input files of this run.")
+	fmt.Fprintf(w, "text2glove_files %d\n", s.Files.Total)
+	family(w, "text2glove_files_processed_total", "counter", "Input files by outcome.")
+	fmt.Fprintf(w, "text2glove_files_processed_total{status=\"processed\"} %d\n", s.Files.Processed)
+	fmt.Fprintf(w, "text2glove_files_processed_total{status=\"failed\"} %d\n", s.Files.Failed)
+	family(w, "text2glove_input_bytes_total", "counter", "Uncompressed bytes read from processed input files.")
+	fmt.Fprintf(w, "text2glove_input_bytes_total %d\n", s.BytesIn)
+	family(w, "text2glove_output_bytes_total", "counter", "Bytes written to the output.")
+	fmt.Fprintf(w, "text2glove_output_bytes_total %d\n", s.BytesOut)
+	family(w, "text2glove_output_lines_total", "counter", "Lines written to the output.")
+	fmt.Fprintf(w, "text2glove_output_lines_total %d\n", s.Lines)
+	family(w, "text2glove_corrupted_lines_total", "counter", "Input lines detected as corrupted.")
+	fmt.Fprintf(w, "text2glove_corrupted_lines_total %d\n", s.Corrupted)
+
+	family(w, "text2glove_errors_total", "counter", "Processing errors by class.")
+	for _, class := range sortedKeys(s.Errors) {
+		fmt.Fprintf(w, "text2glove_errors_total{class=%q} %d\n", class, s.Errors[class])
+	}
+
+	family(w, "text2glove_worker_busy_seconds_total", "counter", "Time each worker spent processing files.")
+	for i, busy := range s.Workers {
+		fmt.Fprintf(w, "text2glove_worker_busy_seconds_total{worker=\"%d\"} %g\n", i+1, busy)
+	}
+
+	family(w, "text2glove_mystem_duration_seconds", "histogram", "Duration of mystem calls.")
+	m.mystemMu.Lock()
+	var cumulative uint64
+	for i, le := range mystemBuckets {
+		cumulative += m.mystemCounts[i]
+		fmt.Fprintf(w, "text2glove_mystem_duration_seconds_bucket{le=\"%g\"} %d\n", le, cumulative)
+	}
+	fmt.Fprintf(w, "text2glove_mystem_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.mystemCount)
+	fmt.Fprintf(w, "text2glove_mystem_duration_seconds_sum %g\n", m.mystemSum)
+	fmt.Fprintf(w, "text2glove_mystem_duration_seconds_count %d\n", m.mystemCount)
+	m.mystemMu.Unlock()
+
+	family(w, "text2glove_queue_depth", "gauge", "Items waiting in a pipeline queue.")
+	names := sortedKeys(s.Queues)
+	for _, name := range names {
+		fmt.Fprintf(w, "text2glove_queue_depth{queue=%q} %d\n", name, s.Queues[name].Length)
+	}
+	family(w, "text2glove_queue_capacity", "gauge", "Capacity of a pipeline queue.")
+	for _, name := range names {
+		fmt.Fprintf(w, "text2glove_queue_capacity{queue=%q} %d\n", name, s.Queues[name].Capacity)
+	}
+
+	family(w, "text2glove_uptime_seconds", "gauge", "Seconds since the run started.")
+	fmt.Fprintf(w, "text2glove_uptime_seconds %g\n", s.Uptime)
+}
+
+func family(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
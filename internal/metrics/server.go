@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// Status — состояние запуска для /status.
+type Status struct {
+	State     string    `json:"state"`
+	Started   time.Time `json:"started"`
+	Uptime    float64   `json:"uptime_seconds"`
+	Files     Files     `json:"files"`
+	Progress  float64   `json:"progress"` // доля завершённых файлов, от 0 до 1
+	BytesIn   uint64    `json:"bytes_in"`
+	BytesOut  uint64    `json:"bytes_out"`
+	Lines     uint64    `json:"lines"`
+	Corrupted uint64    `json:"corrupted_lines"`
+
+	Errors  map[string]int        `json:"errors"`
+	Queues  map[string]QueueDepth `json:"queues"`
+	Workers []float64             `json:"worker_busy_seconds"`
+}
+
+// Files — входные файлы запуска.
+type Files struct {
+	Total     int64  `json:"total"`
+	Processed uint64 `json:"processed"`
+	Failed    uint64 `json:"failed"`
+}
+
+// QueueDepth — глубина очереди.
+type QueueDepth struct {
+	Length   int `json:"length"`
+	Capacity int `json:"capacity"`
+}
+
+// Status возвращает снимок показателей.
+func (m *Metrics) Status() Status {
+	s := Status{
+		Started: m.started.UTC(),
+		Uptime:  time.Since(m.started).Seconds(),
+		Files: Files{
+			Total:     m.total.Load(),
+			Processed: m.processed.Load(),
+			Failed:    m.failed.Load(),
+		},
+		BytesIn: m.inputBytes.Load(),
+		Errors:  make(map[string]int),
+		Queues:  make(map[string]QueueDepth),
+		Workers: make([]float64, len(m.busy)),
+	}
+	if s.Files.Total > 0 {
+		s.Progress = float64(s.Files.Processed+s.Files.Failed) / float64(s.Files.Total)
+	}
+	for i := range m.busy {
+		s.Workers[i] = time.Duration(m.busy[i].Load()).Seconds()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s.State = m.state
+	if m.output != nil {
+		output := m.output()
+		s.BytesOut, s.Lines, s.Corrupted = output.Bytes, output.Lines, output.Corrupted
+	}
+	if m.errors != nil {
+		s.Errors = m.errors()
+	}
+	for name, q := range m.queues {
+		s.Queues[name] = QueueDepth{Length: q.length(), Capacity: q.capacity}
+	}
+	return s
+}
+
+// Serve запускает HTTP-сервер на addr: /metrics (Prometheus), /status
+// (JSON) и /debug/pprof. Сервер работает до выхода из программы.
+func Serve(addr string, m *Metrics) (string, error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteText(w)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(m.Status())
+	})
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	// Порт занимается сразу, чтобы ошибка (например, порт занят) была
+	// видна до начала обработки
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to listen on %s: %v", addr, err)
+	}
+	go http.Serve(listener, mux)
+	return listener.Addr().String(), nil
+}
@@ -0,0 +1,278 @@
+package phrases
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/terratensor/text2glove/internal/cleaner"
+	"github.com/terratensor/text2glove/internal/vocab"
+)
+
+// Scoring — функция оценки пары слов.
+type Scoring string
+
+const (
+	// ScoringDefault — оценка word2phrase: (n(ab) - min_count) / n(a) / n(b) * N.
+	ScoringDefault Scoring = "default"
+	// ScoringNPMI — нормированная поточечная взаимная информация, от -1 до 1.
+	ScoringNPMI Scoring = "npmi"
+)
+
+// DefaultMaxVocab — предел числа различных слов и пар в памяти.
+const DefaultMaxVocab = 20_000_000
+
+// ParseScoring проверяет имя функции оценки.
+func ParseScoring(s string) (Scoring, error) {
+	switch scoring := Scoring(s); scoring {
+	case ScoringDefault, ScoringNPMI:
+		return scoring, nil
+	}
+	return "", fmt.Errorf("unknown scoring %q: expected default|npmi", s)
+}
+
+// Options задаёт параметры поиска словосочетаний.
+type Options struct {
+	MinCount   uint64    // пары и слова реже min_count не объединяются
+	Thresholds []float64 // порог оценки для каждого прохода
+	Scoring    Scoring
+	Delimiter  string // соединитель слов во фразе, обычно "_"
+	MaxVocab   int    // предел таблицы счётчиков (0 — DefaultMaxVocab)
+	TempDir    string // каталог для промежуточных проходов (пусто — системный)
+}
+
+// Phrase — найденное словосочетание.
+type Phrase struct {
+	Phrase string
+	Count  uint64  // сколько раз пара встретилась при подсчёте
+	Score  float64 // оценка пары
+	Pass   int     // номер прохода, на котором пара объединена
+	Joined uint64  // сколько раз пара объединена в корпусе
+}
+
+// Stats — итоги прохода.
+type Stats struct {
+	Tokens uint64 // токенов во входе прохода
+	Joined uint64 // объединённых пар
+}
+
+// Run выполняет len(opts.Thresholds) проходов над корпусом input и пишет
+// результат последнего прохода в out. На каждом проходе сначала считаются
+// слова и пары соседних слов, затем пары с оценкой выше порога
+// объединяются через Delimiter. Следующий проход работает с уже
+// объединёнными токенами, поэтому находит и более длинные фразы.
+func Run(input string, out io.Writer, opts Options) ([]Phrase, []Stats, error) {
+	if len(opts.Thresholds) == 0 {
+		return nil, nil, fmt.Errorf("no thresholds given")
+	}
+	if opts.Delimiter == "" {
+		opts.Delimiter = "_"
+	}
+	if opts.MaxVocab <= 0 {
+		opts.MaxVocab = DefaultMaxVocab
+	}
+
+	tempDir, err := os.MkdirTemp(opts.TempDir, "text2glove-phrases-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var table []Phrase
+	var allStats []Stats
+	source := input
+	for pass, threshold := range opts.Thresholds {
+		counts, err := count(source, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var dst io.Writer = out
+		var tempFile *os.File
+		if pass < len(opts.Thresholds)-1 {
+			tempFile, err = os.Create(filepath.Join(tempDir, fmt.Sprintf("pass-%d.txt", pass+1)))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create temp file: %v", err)
+			}
+			dst = tempFile
+		}
+
+		found, joined, err := rewrite(source, dst, counts, threshold, opts)
+		if tempFile != nil {
+			tempFile.Close()
+			source = tempFile.Name()
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, p := range found {
+			p.Pass = pass + 1
+			table = append(table, *p)
+		}
+		allStats = append(allStats, Stats{Tokens: counts.total, Joined: joined})
+	}
+
+	sort.SliceStable(table, func(i, j int) bool {
+		if table[i].Pass != table[j].Pass {
+			return table[i].Pass < table[j].Pass
+		}
+		return table[i].Score > table[j].Score
+	})
+	return table, allStats, nil
+}
+
+// WriteTable пишет таблицу фраз в TSV: фраза, проход, частота пары,
+// число объединений и оценка.
+func WriteTable(w io.Writer, table []Phrase) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("phrase\tpass\tcount\tjoined\tscore\n")
+	for _, p := range table {
+		fmt.Fprintf(bw, "%s\t%d\t%d\t%d\t%s\n", p.Phrase, p.Pass, p.Count, p.Joined,
+			strconv.FormatFloat(p.Score, 'f', 6, 64))
+	}
+	return bw.Flush()
+}
+
+// counts — частоты слов и пар одного прохода.
+type counts struct {
+	words     map[string]uint64 // слова и пары "a b"
+	total     uint64            // всего токенов
+	minReduce uint64            // пары с частотой ниже этого значения удалялись
+}
+
+// count считает слова и пары соседних слов. Пары не пересекают границы
+// строк и не включают токены-заменители (<NUM>). Когда таблица превышает
+// MaxVocab, редкие записи удаляются, как ReduceVocab в word2phrase.
+func count(path string, opts Options) (*counts, error) {
+	c := &counts{words: make(map[string]uint64), minReduce: 1}
+	err := eachLine(path, func(line string) error {
+		prev := ""
+		for _, word := range strings.Fields(line) {
+			c.total++
+			c.words[word]++
+			if prev != "" && joinable(word) {
+				c.words[prev+" "+word]++
+			}
+			prev = ""
+			if joinable(word) {
+				prev = word
+			}
+			if len(c.words) > opts.MaxVocab {
+				c.reduce()
+			}
+		}
+		return nil
+	})
+	return c, err
+}
+
+func (c *counts) reduce() {
+	for key, n := range c.words {
+		if n <= c.minReduce {
+			delete(c.words, key)
+		}
+	}
+	c.minReduce++
+}
+
+// score оценивает пару; 0 (или -1 для npmi), если слово или пара редки.
+func (c *counts) score(a, b string, opts Options) (float64, uint64) {
+	pa, pb, pab := c.words[a], c.words[b], c.words[a+" "+b]
+	if pa < opts.MinCount || pb < opts.MinCount || pab < opts.MinCount || pab == 0 {
+		if opts.Scoring == ScoringNPMI {
+			return -1, pab
+		}
+		return 0, pab
+	}
+	n := float64(c.total)
+	if opts.Scoring == ScoringNPMI {
+		pmi := math.Log(float64(pab) * n / (float64(pa) * float64(pb)))
+		return pmi / -math.Log(float64(pab)/n), pab
+	}
+	return (float64(pab) - float64(opts.MinCount)) / float64(pa) / float64(pb) * n, pab
+}
+
+// rewrite объединяет пары с оценкой выше threshold. Как в word2phrase,
+// слово, вошедшее во фразу, не объединяется со следующим на том же проходе.
+func rewrite(path string, out io.Writer, c *counts, threshold float64, opts Options) ([]*Phrase, uint64, error) {
+	found := make(map[string]*Phrase)
+	var joined uint64
+	bw := bufio.NewWriterSize(out, 1024*1024)
+
+	err := eachLine(path, func(line string) error {
+		words := strings.Fields(line)
+		for i := 0; i < len(words); i++ {
+			if i > 0 {
+				bw.WriteByte(' ')
+			}
+			word := words[i]
+			if i+1 < len(words) && joinable(word) && joinable(words[i+1]) {
+				if score, pab := c.score(word, words[i+1], opts); score > threshold {
+					phrase := word + opts.Delimiter + words[i+1]
+					p, ok := found[phrase]
+					if !ok {
+						p = &Phrase{Phrase: phrase, Count: pab, Score: score}
+						found[phrase] = p
+					}
+					p.Joined++
+					joined++
+					word = phrase
+					i++
+				}
+			}
+			bw.WriteString(word)
+		}
+		_, err := bw.WriteString("\n")
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, 0, fmt.Errorf("failed to write output: %v", err)
+	}
+
+	list := make([]*Phrase, 0, len(found))
+	for _, p := range found {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Phrase < list[j].Phrase })
+	return list, joined, nil
+}
+
+// joinable сообщает, может ли токен входить во фразу.
+func joinable(word string) bool {
+	return !cleaner.IsPlaceholder(word)
+}
+
+// eachLine вызывает fn для каждой строки корпуса; строки могут быть
+// сколь угодно длинными (документ в строке).
+func eachLine(path string, fn func(line string) error) error {
+	r, err := vocab.OpenCorpus(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	br := bufio.NewReaderSize(r, 1024*1024)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			if ferr := fn(strings.TrimSuffix(line, "\n")); ferr != nil {
+				return ferr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read corpus %s: %v", path, err)
+		}
+	}
+}
@@ -2,54 +2,116 @@ package processor
 
 import (
 	"bufio"
+	"compress/flate"
 	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/terratensor/text2glove/internal/cleaner"
 	"github.com/terratensor/text2glove/internal/detector"
+	"github.com/terratensor/text2glove/internal/failures"
+	"github.com/terratensor/text2glove/internal/filter"
+	"github.com/terratensor/text2glove/internal/hyphenation"
 	"github.com/terratensor/text2glove/internal/lemmatizer"
+	"github.com/terratensor/text2glove/internal/metrics"
+	"github.com/terratensor/text2glove/internal/tokenizer"
 	"github.com/terratensor/text2glove/internal/writer"
 )
 
 const (
-	maxTokenSize = 10 * 1024 * 1024 // 10MB
+	maxTokenSize   = 10 * 1024 * 1024 // 10MB
+	retryDelay     = 500 * time.Millisecond
+	encodingSample = 64 * 1024 // сколько байт начала файла смотрит определитель кодировки
 )
 
 type FileProcessor struct {
 	cleaner    *cleaner.TextCleaner
 	lemmatizer *lemmatizer.Lemmatizer
 	lemmatize  bool
+	joiner     *hyphenation.Joiner  // nil — склейка переносов отключена
+	segmenter  *tokenizer.Segmenter // nil — документ целиком в одну строку
+	filter     *filter.TokenFilter  // nil — токены не фильтруются
+	failures   *failures.Tracker
+	metrics    *metrics.Metrics // nil — метрики не собираются
 }
 
-func New(cleaner *cleaner.TextCleaner, lemmatizer *lemmatizer.Lemmatizer, lemmatize bool) *FileProcessor {
+func New(cleaner *cleaner.TextCleaner, lemmatizer *lemmatizer.Lemmatizer, lemmatize bool, joiner *hyphenation.Joiner, segmenter *tokenizer.Segmenter, filter *filter.TokenFilter, failures *failures.Tracker, metrics *metrics.Metrics) *FileProcessor {
 	return &FileProcessor{
 		cleaner:    cleaner,
 		lemmatizer: lemmatizer,
 		lemmatize:  lemmatize,
+		joiner:     joiner,
+		segmenter:  segmenter,
+		filter:     filter,
+		failures:   failures,
+		metrics:    metrics,
 	}
 }
 
-func (p *FileProcessor) Work(id int, fileChan <-chan string, textChan chan<- string, progressChan chan<- int, resultWriter *writer.ResultWriter) {
+// Job — входной файл и его номер в порядке обхода.
+type Job struct {
+	Seq  int
+	Path string
+}
+
+// Work обрабатывает файлы из fileChan, пока канал не закрыт или не отменён
+// ctx. Документ, обработка которого прервана отменой, не отправляется.
+func (p *FileProcessor) Work(ctx context.Context, id int, fileChan <-chan Job, docChan chan<- writer.Document, progressChan chan<- int, resultWriter *writer.ResultWriter) {
 	var processed, corrupted int
 
-	for file := range fileChan {
-		text, err := p.processFile(file, resultWriter)
+	for {
+		var job Job
+		select {
+		case <-ctx.Done():
+			return
+		case j, ok := <-fileChan:
+			if !ok {
+				return
+			}
+			job = j
+		}
+		file := job.Path
+
+		attempts := 1
+		started := time.Now()
+		lines, meta, err := p.processFile(ctx, file)
+		for err != nil && ctx.Err() == nil && p.failures.Retry(err, attempts) {
+			fmt.Printf("\r\x1b[33mRetry %d:\x1b[0m %s: %v\n", attempts, file, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(attempts) * retryDelay):
+			}
+			attempts++
+			lines, meta, err = p.processFile(ctx, file)
+		}
+		p.metrics.AddBusy(id, time.Since(started))
+		if ctx.Err() != nil {
+			return
+		}
 		if err != nil {
 			fmt.Printf("\r\x1b[31mError:\x1b[0m %s: %v\n", file, err)
+			p.failures.Fail(file, err, attempts)
+			p.metrics.FileFailed()
+			// Писатель в упорядоченном режиме ждёт каждый номер
+			docChan <- writer.Document{Seq: job.Seq, Source: file, Failed: true}
 			continue
 		}
+		resultWriter.AddCorrupted(meta.Corrupted)
+		p.metrics.FileProcessed(meta.SourceBytes)
 
-		if text != "" {
-			// if detector.IsCorrupted(text) {
-			// 	corrupted++
-			// 	resultWriter.IncrementCorrupted()
-			// 	continue // Пропускаем битые тексты
-			// }
-			textChan <- text
-		}
+		// if detector.IsCorrupted(text) {
+		// 	corrupted++
+		// 	resultWriter.IncrementCorrupted()
+		// 	continue // Пропускаем битые тексты
+		// }
+		// Пустой документ тоже отправляется: писатель отмечает файл в журнале
+		docChan <- writer.Document{Seq: job.Seq, Source: file, Lines: lines, Meta: meta}
 
 		processed++
 		if processed%100 == 0 {
@@ -62,27 +124,45 @@ func (p *FileProcessor) Work(id int, fileChan <-chan string, textChan chan<- str
 	}
 }
 
-func (p *FileProcessor) processFile(filePath string, resultWriter *writer.ResultWriter) (string, error) {
+// processFile возвращает строки документа (одну строку в режиме document
+// или предложения в режиме sentence) и метаданные источника: размер,
+// язык, кодировку, число битых строк. Ошибки помечены классом для отчёта.
+func (p *FileProcessor) processFile(ctx context.Context, filePath string) ([]string, *writer.Metadata, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open file: %v", err)
+		return nil, nil, failures.Wrap(failures.ClassOpen, fmt.Errorf("failed to open file: %v", err))
 	}
 	defer file.Close()
 
 	gz, err := gzip.NewReader(file)
 	if err != nil {
-		return "", fmt.Errorf("gzip error: %v", err)
+		return nil, nil, failures.Wrap(failures.ClassGzip, fmt.Errorf("gzip error: %v", err))
 	}
 	defer gz.Close()
 
+	// builder накапливает очищенный текст, а в режиме предложений —
+	// исходный: делить на предложения нужно до очистки
 	var builder strings.Builder
 	scanner := bufio.NewScanner(gz)
 
 	buf := make([]byte, 0, maxTokenSize)
 	scanner.Buffer(buf, maxTokenSize)
 
+	// tail — слово, оборванное переносом в конце предыдущей строки
+	var tail string
+	var joiner *hyphenation.Joiner
+	if p.joiner != nil {
+		joiner = p.joiner.ForDocument()
+	}
+	meta := &writer.Metadata{Source: filePath, Lemmatization: writer.LemmatizationOff}
+	var sample []byte
 	for scanner.Scan() {
 		line := scanner.Text()
+		meta.SourceBytes += int64(len(line)) + 1
+		meta.SourceLines++
+		if len(sample) < encodingSample {
+			sample = append(sample, scanner.Bytes()...)
+		}
 		if detector.IsCorrupted(line) {
 			// corrupted++
 			if len(line) > 100 {
@@ -90,31 +170,120 @@ func (p *FileProcessor) processFile(filePath string, resultWriter *writer.Result
 			} else {
 				log.Printf("Corrupted line: %s", line)
 			}
-			resultWriter.IncrementCorrupted()
+			meta.Corrupted++
 			// continue // Пропускаем битые тексты
 		}
-		cleanLine := p.cleaner.Clean(line)
-		if cleanLine != "" {
-			builder.WriteString(cleanLine)
-			builder.WriteRune(' ')
+		if joiner != nil {
+			line, tail = joiner.Join(tail, line)
 		}
+		p.appendLine(&builder, line)
 	}
+	p.appendLine(&builder, tail)
 
 	if err := scanner.Err(); err != nil {
-		return "", fmt.Errorf("scanner error: %v", err)
+		return nil, nil, failures.Wrap(readErrorClass(err), fmt.Errorf("scanner error: %v", err))
+	}
+	meta.Encoding = detector.Encoding(sample)
+
+	if p.segmenter != nil {
+		sentences, err := p.processSentences(ctx, builder.String(), meta)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sentences, meta, nil
 	}
 
-	content := builder.String()
+	content := p.applyFilter(builder.String(), filePath)
+	meta.Language = detector.Language(content)
 	// Применяем лемматизацию
-	// Передаем имя файла в лемматизатор
 	if p.lemmatize && p.lemmatizer != nil {
-		lemmatized, err := p.lemmatizer.Lemmatize(content, filePath) // передаем filePath
+		started := time.Now()
+		lemmatized, err := p.lemmatizer.Lemmatize(ctx, content)
+		p.metrics.ObserveMystem(time.Since(started))
+		if err != nil && ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
+		if err != nil {
+			// Документ без лемматизации не пишется: он выбивался бы из корпуса
+			return nil, nil, failures.Wrap(failures.ClassMystem, fmt.Errorf("lemmatization failed: %v", err))
+		}
+		meta.Lemmatization = writer.LemmatizationDone
+		// Повторная фильтрация отбрасывает стоп-слова, появившиеся как леммы
+		content = p.applyFilter(lemmatized, filePath)
+	}
+
+	if content == "" {
+		return nil, meta, nil
+	}
+	return []string{content}, meta, nil
+}
+
+// readErrorClass различает повреждённый архив и ошибки сканера
+// (например, строку длиннее maxTokenSize).
+func readErrorClass(err error) failures.Class {
+	var corrupt flate.CorruptInputError
+	switch {
+	case errors.Is(err, bufio.ErrTooLong):
+		return failures.ClassScanner
+	case errors.Is(err, gzip.ErrChecksum), errors.Is(err, gzip.ErrHeader),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &corrupt):
+		return failures.ClassGzip
+	}
+	return failures.ClassScanner
+}
+
+func (p *FileProcessor) applyFilter(text, filePath string) string {
+	if p.filter == nil {
+		return text
+	}
+	return p.filter.Apply(text, filePath)
+}
+
+func (p *FileProcessor) appendLine(builder *strings.Builder, line string) {
+	if p.segmenter != nil {
+		builder.WriteString(line)
+		builder.WriteRune('\n')
+		return
+	}
+	cleanLine := p.cleaner.Clean(line)
+	if cleanLine != "" {
+		builder.WriteString(cleanLine)
+		builder.WriteRune(' ')
+	}
+}
+
+// processSentences делит исходный текст на предложения, очищает и
+// токенизирует каждое и возвращает непустые предложения.
+func (p *FileProcessor) processSentences(ctx context.Context, text string, meta *writer.Metadata) ([]string, error) {
+	filePath := meta.Source
+	var sentences []string
+	for _, sentence := range p.segmenter.Split(text) {
+		words := tokenizer.Words(p.cleaner.Clean(sentence))
+		if filtered := p.applyFilter(strings.Join(words, " "), filePath); filtered != "" {
+			sentences = append(sentences, filtered)
+		}
+	}
+
+	meta.Language = detector.Language(strings.Join(sentences, " "))
+
+	if p.lemmatize && p.lemmatizer != nil && len(sentences) > 0 {
+		started := time.Now()
+		lemmatized, err := p.lemmatizer.LemmatizeLines(ctx, sentences)
+		p.metrics.ObserveMystem(time.Since(started))
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if err != nil {
-			log.Printf("Lemmatization failed for file %s: %v", filePath, err)
-		} else {
-			content = lemmatized
+			return nil, failures.Wrap(failures.ClassMystem, fmt.Errorf("lemmatization failed: %v", err))
+		}
+		meta.Lemmatization = writer.LemmatizationDone
+		sentences = sentences[:0]
+		for _, sentence := range lemmatized {
+			if filtered := p.applyFilter(sentence, filePath); filtered != "" {
+				sentences = append(sentences, filtered)
+			}
 		}
 	}
 
-	return content, nil
+	return sentences, nil
 }
@@ -0,0 +1,188 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+)
+
+// Размеры диаграмм в пикселях.
+const (
+	chartWidth  = 640 // ширина горизонтальных диаграмм
+	labelWidth  = 140 // место под подписи слева
+	barHeight   = 18
+	columnWidth = 36
+	chartHeight = 200 // высота гистограмм
+)
+
+// hbar — строка горизонтальной диаграммы.
+type hbar struct {
+	Label string
+	Count uint64
+	Y     int
+	Width float64
+}
+
+// column — столбец гистограммы.
+type column struct {
+	Label  string
+	Count  uint64
+	X      int
+	Y      float64
+	Height float64
+}
+
+type hchart struct {
+	Height int
+	Bars   []hbar
+}
+
+type vchart struct {
+	Width   int
+	Columns []column
+}
+
+func barChart(counts []Count) hchart {
+	var peak uint64
+	for _, c := range counts {
+		peak = max(peak, c.Count)
+	}
+	chart := hchart{Height: len(counts) * barHeight}
+	for i, c := range counts {
+		chart.Bars = append(chart.Bars, hbar{
+			Label: c.Name,
+			Count: c.Count,
+			Y:     i * barHeight,
+			Width: scale(c.Count, peak, chartWidth-labelWidth-80),
+		})
+	}
+	return chart
+}
+
+func histogram(buckets []Bucket) vchart {
+	var peak uint64
+	for _, b := range buckets {
+		peak = max(peak, b.Count)
+	}
+	chart := vchart{Width: len(buckets) * columnWidth}
+	for i, b := range buckets {
+		h := scale(b.Count, peak, chartHeight)
+		chart.Columns = append(chart.Columns, column{
+			Label:  b.Label,
+			Count:  b.Count,
+			X:      i * columnWidth,
+			Y:      chartHeight - h,
+			Height: h,
+		})
+	}
+	return chart
+}
+
+func scale(value, peak uint64, size float64) float64 {
+	if peak == 0 {
+		return 0
+	}
+	return float64(value) / float64(peak) * size
+}
+
+func groupCounts(groups []Group) []Count {
+	counts := make([]Count, len(groups))
+	for i, g := range groups {
+		counts[i] = Count{Name: g.Name, Count: g.Tokens}
+	}
+	return counts
+}
+
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bars":   barChart,
+	"hist":   histogram,
+	"groups": groupCounts,
+	"add":    func(a, b int) int { return a + b },
+}).Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Отчёт о корпусе {{.Output}}</title>
+<style>
+body { font: 14px/1.4 sans-serif; margin: 2em; color: #222; max-width: 60em; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.15em; margin-top: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 2px 10px; text-align: right; border-bottom: 1px solid #eee; }
+td:first-child, th:first-child { text-align: left; }
+svg text { font: 11px sans-serif; fill: #333; }
+.bar { fill: #4a7ab5; }
+</style>
+</head>
+<body>
+<h1>Корпус {{.Output}}</h1>
+<p>Создан {{.Created.Format "2006-01-02 15:04:05"}} UTC</p>
+
+<table>
+<tr><td>Документов</td><td>{{.Documents}}</td></tr>
+<tr><td>Строк</td><td>{{.Lines}}</td></tr>
+<tr><td>Токенов</td><td>{{.Tokens}}</td></tr>
+<tr><td>Типов</td><td>{{.Types}}</td></tr>
+<tr><td>TTR</td><td>{{printf "%.4f" .TTR}}</td></tr>
+<tr><td>Средняя длина документа, токенов</td><td>{{printf "%.1f" .MeanDocumentLength}}</td></tr>
+<tr><td>Средняя длина токена, символов</td><td>{{printf "%.2f" .MeanTokenLength}}</td></tr>
+</table>
+
+{{define "hbars"}}<svg width="640" height="{{add .Height 4}}">
+{{range .Bars}}<text x="136" y="{{add .Y 13}}" text-anchor="end">{{.Label}}</text>
+<rect class="bar" x="140" y="{{add .Y 2}}" width="{{printf "%.1f" .Width}}" height="14"/>
+<text x="{{printf "%.1f" .Width}}" dx="144" y="{{add .Y 13}}">{{.Count}}</text>
+{{end}}</svg>{{end}}
+
+{{define "hist"}}<svg width="{{add .Width 4}}" height="270">
+{{range .Columns}}<rect class="bar" x="{{add .X 2}}" y="{{printf "%.1f" .Y}}" width="32" height="{{printf "%.1f" .Height}}"><title>{{.Label}}: {{.Count}}</title></rect>
+<text x="{{add .X 22}}" y="214" text-anchor="end" transform="rotate(-45 {{add .X 22}} 214)">{{.Label}}</text>
+{{end}}</svg>{{end}}
+
+<h2>Длина документов, токенов</h2>
+{{template "hist" (hist .DocumentLengths)}}
+
+<h2>Длина токенов, символов</h2>
+{{template "hist" (hist .TokenLengths)}}
+
+<h2>Символы по письменностям</h2>
+{{template "hbars" (bars .Scripts)}}
+
+<h2>Языки</h2>
+<table>
+<tr><th>Язык</th><th>Документов</th><th>Токенов</th><th>Типов (≈)</th><th>TTR</th></tr>
+{{range .Languages}}<tr><td>{{.Name}}</td><td>{{.Documents}}</td><td>{{.Tokens}}</td><td>{{.Types}}</td><td>{{printf "%.4f" .TTR}}</td></tr>
+{{end}}</table>
+{{template "hbars" (bars (groups .Languages))}}
+
+<h2>Жанры</h2>
+<table>
+<tr><th>Жанр</th><th>Документов</th><th>Токенов</th><th>Типов (≈)</th><th>TTR</th></tr>
+{{range .Genres}}<tr><td>{{.Name}}</td><td>{{.Documents}}</td><td>{{.Tokens}}</td><td>{{.Types}}</td><td>{{printf "%.4f" .TTR}}</td></tr>
+{{end}}</table>
+{{template "hbars" (bars (groups .Genres))}}
+
+<h2>Отброшено при обработке</h2>
+<table>
+{{range .Dropped}}<tr><td>{{.Name}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+
+<h2>Частые токены</h2>
+{{template "hbars" (bars .Top)}}
+</body>
+</html>
+`))
+
+// WriteHTML записывает отчёт самостоятельной HTML-страницей: диаграммы
+// встроены в SVG, внешних файлов и скриптов нет.
+func (r *Report) WriteHTML(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %v", err)
+	}
+	if err := page.Execute(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	return file.Close()
+}
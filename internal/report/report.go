@@ -0,0 +1,288 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTop — сколько самых частых токенов попадает в отчёт по умолчанию.
+const DefaultTop = 100
+
+// maxTokenLength — токены длиннее попадают в последний столбец гистограммы.
+const maxTokenLength = 20
+
+// Письменности гистограммы символов в порядке вывода.
+var scripts = []string{"cyrillic", "latin", "greek", "digit", "punct", "other"}
+
+// Unlabeled — группа документов без языка или жанра.
+const Unlabeled = "none"
+
+// Count — именованный счётчик.
+type Count struct {
+	Name  string `json:"name"`
+	Count uint64 `json:"count"`
+}
+
+// Bucket — столбец гистограммы: значения от Min до Max включительно.
+type Bucket struct {
+	Label string `json:"label"`
+	Min   int    `json:"min"`
+	Max   int    `json:"max"` // 0 — без верхней границы
+	Count uint64 `json:"count"`
+}
+
+// Group — срез корпуса по языку или жанру.
+type Group struct {
+	Name      string  `json:"name"`
+	Documents uint64  `json:"documents"`
+	Tokens    uint64  `json:"tokens"`
+	Types     uint64  `json:"types"` // оценка, погрешность около 1%
+	TTR       float64 `json:"ttr"`
+}
+
+// Report — статистика корпуса.
+type Report struct {
+	Created time.Time `json:"created"`
+	Output  string    `json:"output"`
+
+	Documents uint64  `json:"documents"`
+	Lines     uint64  `json:"lines"`
+	Tokens    uint64  `json:"tokens"`
+	Types     uint64  `json:"types"`
+	TTR       float64 `json:"ttr"` // отношение числа типов к числу токенов
+
+	MeanDocumentLength float64 `json:"mean_document_length"` // токенов в документе
+	MeanTokenLength    float64 `json:"mean_token_length"`    // символов в токене
+
+	Top             []Count  `json:"top"`
+	DocumentLengths []Bucket `json:"document_lengths"`
+	TokenLengths    []Bucket `json:"token_lengths"`
+	Scripts         []Count  `json:"scripts"`
+	Languages       []Group  `json:"languages"`
+	Genres          []Group  `json:"genres"`
+	Dropped         []Count  `json:"dropped"` // что отброшено при обработке, по причинам
+}
+
+// Collector набирает статистику по записанным документам. Писатель
+// вызывает Add из одной горутины, поэтому блокировок нет.
+type Collector struct {
+	top int
+
+	documents uint64
+	lines     uint64
+	tokens    uint64
+	runes     uint64
+	types     map[string]uint64
+
+	documentLengths [32]uint64 // по степеням двойки: [2^i, 2^(i+1))
+	tokenLengths    [maxTokenLength + 1]uint64
+	scripts         map[string]uint64
+	languages       map[string]*groupCounter
+	genres          map[string]*groupCounter
+}
+
+type groupCounter struct {
+	documents uint64
+	tokens    uint64
+	types     typeSketch
+}
+
+// NewCollector создаёт сборщик статистики; top — сколько самых частых
+// токенов войдёт в отчёт.
+func NewCollector(top int) *Collector {
+	if top <= 0 {
+		top = DefaultTop
+	}
+	return &Collector{
+		top:       top,
+		types:     make(map[string]uint64),
+		scripts:   make(map[string]uint64),
+		languages: make(map[string]*groupCounter),
+		genres:    make(map[string]*groupCounter),
+	}
+}
+
+// Add учитывает документ из строк lines. Метки fastText (__label__...) —
+// не слова корпуса и не считаются.
+func (c *Collector) Add(lines []string, language, genre string) {
+	lang := c.group(c.languages, language)
+	gen := c.group(c.genres, genre)
+
+	var tokens uint64
+	for _, line := range lines {
+		c.lines++
+		for _, token := range strings.Fields(line) {
+			if strings.HasPrefix(token, "__label__") {
+				continue
+			}
+			tokens++
+			c.types[token]++
+			lang.types.add(token)
+			gen.types.add(token)
+
+			n := utf8.RuneCountInString(token)
+			c.runes += uint64(n)
+			c.tokenLengths[min(n, maxTokenLength)]++
+			for _, r := range token {
+				c.scripts[script(r)]++
+			}
+		}
+	}
+
+	c.documents++
+	c.tokens += tokens
+	if tokens > 0 {
+		c.documentLengths[bits.Len64(tokens)-1]++
+	}
+	lang.documents++
+	lang.tokens += tokens
+	gen.documents++
+	gen.tokens += tokens
+}
+
+func (c *Collector) group(groups map[string]*groupCounter, name string) *groupCounter {
+	if name == "" {
+		name = Unlabeled
+	}
+	g, ok := groups[name]
+	if !ok {
+		g = &groupCounter{}
+		groups[name] = g
+	}
+	return g
+}
+
+func script(r rune) string {
+	switch {
+	case unicode.Is(unicode.Cyrillic, r):
+		return "cyrillic"
+	case unicode.Is(unicode.Latin, r):
+		return "latin"
+	case unicode.Is(unicode.Greek, r):
+		return "greek"
+	case unicode.IsDigit(r):
+		return "digit"
+	case unicode.IsPunct(r) || unicode.IsSymbol(r):
+		return "punct"
+	}
+	return "other"
+}
+
+// Report собирает отчёт; dropped — счётчики отброшенного, которые ведут
+// фильтры, писатель и трекер ошибок.
+func (c *Collector) Report(output string, dropped []Count) *Report {
+	r := &Report{
+		Created:   time.Now().UTC(),
+		Output:    output,
+		Documents: c.documents,
+		Lines:     c.lines,
+		Tokens:    c.tokens,
+		Types:     uint64(len(c.types)),
+		TTR:       ratio(uint64(len(c.types)), c.tokens),
+		Dropped:   dropped,
+	}
+	r.MeanDocumentLength = ratio(c.tokens, c.documents)
+	r.MeanTokenLength = ratio(c.runes, c.tokens)
+
+	r.Top = c.topTokens()
+	r.DocumentLengths = c.documentHistogram()
+	for n := 1; n <= maxTokenLength; n++ {
+		bucket := Bucket{Label: fmt.Sprint(n), Min: n, Max: n, Count: c.tokenLengths[n]}
+		if n == maxTokenLength {
+			bucket.Label, bucket.Max = fmt.Sprintf("%d+", n), 0
+		}
+		r.TokenLengths = append(r.TokenLengths, bucket)
+	}
+	for _, name := range scripts {
+		r.Scripts = append(r.Scripts, Count{Name: name, Count: c.scripts[name]})
+	}
+	r.Languages = groups(c.languages)
+	r.Genres = groups(c.genres)
+	return r
+}
+
+func (c *Collector) topTokens() []Count {
+	top := make([]Count, 0, len(c.types))
+	for token, count := range c.types {
+		top = append(top, Count{Name: token, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Name < top[j].Name
+	})
+	if len(top) > c.top {
+		top = top[:c.top]
+	}
+	return top
+}
+
+// documentHistogram возвращает столбцы от 1 токена до самого длинного
+// документа по степеням двойки: 1, 2–3, 4–7, ...
+func (c *Collector) documentHistogram() []Bucket {
+	last := -1
+	for i, count := range c.documentLengths {
+		if count > 0 {
+			last = i
+		}
+	}
+	buckets := make([]Bucket, 0, last+1)
+	for i := 0; i <= last; i++ {
+		lo, hi := 1<<i, 1<<(i+1)-1
+		label := fmt.Sprintf("%d–%d", lo, hi)
+		if lo == hi {
+			label = fmt.Sprint(lo)
+		}
+		buckets = append(buckets, Bucket{Label: label, Min: lo, Max: hi, Count: c.documentLengths[i]})
+	}
+	return buckets
+}
+
+// groups возвращает срезы по убыванию числа токенов.
+func groups(counters map[string]*groupCounter) []Group {
+	result := make([]Group, 0, len(counters))
+	for name, g := range counters {
+		types := g.types.count()
+		result = append(result, Group{
+			Name:      name,
+			Documents: g.documents,
+			Tokens:    g.tokens,
+			Types:     types,
+			TTR:       ratio(types, g.tokens),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tokens != result[j].Tokens {
+			return result[i].Tokens > result[j].Tokens
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func ratio(a, b uint64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b)
+}
+
+// WriteJSON записывает отчёт в JSON.
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write report: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package report
+
+import (
+	"math"
+	"math/bits"
+)
+
+// sketchPrecision — число бит хеша, выбирающих регистр: 2^14 регистров
+// по байту, относительная ошибка оценки около 1%.
+const sketchPrecision = 14
+
+// typeSketch оценивает число различных токенов среза (HyperLogLog) в
+// постоянной памяти, сколько бы типов ни встретилось.
+type typeSketch struct {
+	registers [1 << sketchPrecision]uint8
+}
+
+func (s *typeSketch) add(token string) {
+	h := hash64(token)
+	i := h >> (64 - sketchPrecision)
+	// Ограничитель снизу не даёт рангу превысить 64-sketchPrecision+1
+	rank := uint8(bits.LeadingZeros64(h<<sketchPrecision|1<<(sketchPrecision-1))) + 1
+	if rank > s.registers[i] {
+		s.registers[i] = rank
+	}
+}
+
+// count возвращает оценку числа различных токенов; на малых числах —
+// линейным подсчётом по пустым регистрам, он там почти точен.
+func (s *typeSketch) count() uint64 {
+	const m = float64(len(s.registers))
+	var sum float64
+	zeros := 0
+	for _, r := range s.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// hash64 — FNV-1a с перемешиванием из MurmurHash3: у FNV старшие биты
+// коротких строк распределены плохо, а регистр выбирается по ним.
+func hash64(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,38 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// Оценка числа типов: на малых числах почти точна, на больших — в
+// пределах нескольких стандартных ошибок HyperLogLog (1.04/√m ≈ 0.8%).
+func TestTypeSketch(t *testing.T) {
+	tests := []struct {
+		types     int
+		tolerance float64
+	}{
+		{0, 0},
+		{1, 0},
+		{100, 0.01},
+		{5000, 0.02},
+		{50000, 0.03},
+		{200000, 0.03},
+		{2000000, 0.03},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.types), func(t *testing.T) {
+			var s typeSketch
+			for i := 0; i < tt.types; i++ {
+				token := fmt.Sprintf("слово%d", i)
+				s.add(token)
+				s.add(token) // повторы не меняют оценку
+			}
+			got := float64(s.count())
+			if diff := math.Abs(got - float64(tt.types)); diff > tt.tolerance*float64(tt.types) {
+				t.Fatalf("count = %.0f, want %d ± %.0f%%", got, tt.types, tt.tolerance*100)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package tokenizer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/terratensor/text2glove/internal/cleaner"
+)
+
+type abbreviationKind int
+
+const (
+	// prefix — сокращение перед именем или названием, предложение после него
+	// не заканчивается: «г. Москва», «Mr. Smith»
+	prefix abbreviationKind = iota + 1
+	// year — «г.», «гг.»: после числа это год и ведёт себя как nonTerminal
+	// («в 1990 г. Потом…»), иначе как prefix («г. Москва»)
+	year
+	// nonTerminal — предложение заканчивается, только если следующее слово
+	// начинается с заглавной буквы: «5 см. Потом…», но «см. рис. 3»
+	nonTerminal
+	// terminal — сокращение может закрывать предложение: «и т.д. Потом…»
+	terminal
+)
+
+// abbreviations — сокращения с точкой, записанные в нижнем регистре без
+// последней точки. Сокращения из нескольких частей записаны слитно: «т. д.»
+// через пробел ищется как «т.д».
+var abbreviations = map[string]abbreviationKind{
+	// русские
+	"г": year, "гг": year,
+	"ул": prefix, "им": prefix, "проф": prefix, "акад": prefix, "тов": prefix,
+	"св": prefix, "пос": prefix, "д": prefix, "кв": prefix, "напр": prefix,
+	"т.е": prefix, "т.к": prefix, "т.н": prefix, "т.ч": prefix,
+	"стр": nonTerminal, "см": nonTerminal, "ср": nonTerminal, "рис": nonTerminal,
+	"табл": nonTerminal, "гл": nonTerminal, "т": nonTerminal, "обл": nonTerminal,
+	"т.д": terminal, "т.п": terminal, "др": terminal, "пр": terminal,
+	"руб": terminal, "коп": terminal, "тыс": terminal, "млн": terminal, "млрд": terminal,
+	"в": terminal, "вв": terminal, "н.э": terminal,
+	// английские
+	"mr": prefix, "mrs": prefix, "ms": prefix, "dr": prefix, "prof": prefix,
+	"st": prefix, "mt": prefix, "gen": prefix, "vs": prefix, "cf": prefix,
+	"e.g": prefix, "i.e": prefix,
+	"jr": nonTerminal, "sr": nonTerminal, "fig": nonTerminal,
+	"etc": terminal, "inc": terminal, "ltd": terminal, "co": terminal, "corp": terminal,
+}
+
+const (
+	openingPunct = "\"'«„“‘([{<"
+	closingPunct = "\"'»“”’)]}>"
+)
+
+// Segmenter делит текст на предложения по правилам для русского и английского
+// языков: учитывает сокращения («т.д.», «г.», «Mr.»), инициалы и регистр
+// следующего слова. Пустая строка всегда завершает предложение.
+type Segmenter struct {
+	abbreviations map[string]abbreviationKind
+}
+
+func NewSegmenter() *Segmenter {
+	return &Segmenter{abbreviations: abbreviations}
+}
+
+// Split возвращает предложения текста. Текст должен сохранять исходный
+// регистр и пунктуацию, поэтому делить нужно до очистки.
+func (s *Segmenter) Split(text string) []string {
+	var sentences []string
+	for _, paragraph := range splitParagraphs(text) {
+		tokens := strings.Fields(paragraph)
+		start := 0
+		for i, token := range tokens {
+			var prev string
+			if i > start {
+				prev = tokens[i-1]
+			}
+			if i == len(tokens)-1 || s.endsSentence(prev, token, tokens[i+1:]) {
+				sentences = append(sentences, strings.Join(tokens[start:i+1], " "))
+				start = i + 1
+			}
+		}
+	}
+	return sentences
+}
+
+// endsSentence решает, завершает ли token предложение, глядя на предыдущий
+// токен того же предложения и следующие токены.
+func (s *Segmenter) endsSentence(prev, token string, next []string) bool {
+	core := strings.TrimRight(token, closingPunct)
+	last, _ := utf8.DecodeLastRuneInString(core)
+
+	switch last {
+	case '!', '?', '…':
+		return !startsLower(next)
+	case '.':
+		if strings.HasSuffix(core, "...") {
+			return !startsLower(next)
+		}
+	default:
+		return false
+	}
+
+	word := strings.TrimLeft(strings.TrimSuffix(core, "."), openingPunct)
+	switch s.abbreviation(prev, word) {
+	case prefix:
+		return false
+	case year:
+		if startsNumber(prev) {
+			return startsUpperLetter(next)
+		}
+		return false
+	case nonTerminal:
+		return startsUpperLetter(next)
+	case terminal:
+		return startsUpper(next)
+	}
+
+	if utf8.RuneCountInString(word) == 1 {
+		r, _ := utf8.DecodeRuneInString(word)
+		if unicode.IsUpper(r) {
+			return false // инициал: «А. С. Пушкин»
+		}
+		if unicode.IsLetter(r) {
+			return startsUpper(next) // «и т. п. Потом»
+		}
+	}
+	return !startsLower(next)
+}
+
+// abbreviation возвращает вид сокращения word. Однобуквенная часть после
+// сокращения с точкой ищется вместе с ним: «т. д.» — как «т.д».
+func (s *Segmenter) abbreviation(prev, word string) abbreviationKind {
+	word = strings.ToLower(word)
+	if utf8.RuneCountInString(word) == 1 && strings.HasSuffix(prev, ".") {
+		first := strings.ToLower(strings.TrimLeft(strings.TrimSuffix(prev, "."), openingPunct))
+		if kind, ok := s.abbreviations[first+"."+word]; ok {
+			return kind
+		}
+	}
+	return s.abbreviations[word]
+}
+
+// Words делит очищенное предложение на слова, отбрасывая пунктуацию по краям
+// токенов. Дефисы, апострофы и точки внутри слова, а также токены-заменители
+// чисел (<NUM>) сохраняются.
+func Words(sentence string) []string {
+	fields := strings.Fields(sentence)
+	words := fields[:0]
+	for _, field := range fields {
+		if cleaner.IsPlaceholder(field) {
+			words = append(words, field)
+			continue
+		}
+		word := strings.TrimFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// splitParagraphs делит текст по пустым строкам.
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	var current strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			if current.Len() > 0 {
+				paragraphs = append(paragraphs, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteString(line)
+		current.WriteByte(' ')
+	}
+	if current.Len() > 0 {
+		paragraphs = append(paragraphs, current.String())
+	}
+	return paragraphs
+}
+
+// firstLetter возвращает первую букву или цифру следующих токенов,
+// пропуская токены из одной пунктуации («—», «"»).
+func firstLetter(next []string) (rune, bool) {
+	for i, token := range next {
+		if i >= 3 {
+			break
+		}
+		for _, r := range token {
+			if unicode.IsLetter(r) || unicode.IsNumber(r) {
+				return r, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func startsLower(next []string) bool {
+	r, ok := firstLetter(next)
+	return ok && unicode.IsLower(r)
+}
+
+func startsUpper(next []string) bool {
+	r, ok := firstLetter(next)
+	return !ok || unicode.IsUpper(r) || unicode.IsNumber(r)
+}
+
+// startsUpperLetter, в отличие от startsUpper, не считает началом
+// предложения число и конец абзаца: после «рис.» идёт номер.
+func startsUpperLetter(next []string) bool {
+	r, ok := firstLetter(next)
+	return ok && unicode.IsUpper(r)
+}
+
+// startsNumber сообщает, начинается ли token с цифры: «1990 г.».
+func startsNumber(token string) bool {
+	r, _ := utf8.DecodeRuneInString(strings.TrimLeft(token, openingPunct))
+	return unicode.IsDigit(r)
+}
@@ -0,0 +1,57 @@
+package tokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegmenterSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"plain", "Он пришёл. Она ушла.", []string{"Он пришёл.", "Она ушла."}},
+		{"question and exclamation", "Правда? Да! Ну...", []string{"Правда?", "Да!", "Ну..."}},
+		{"question before lowercase", "Правда? да, правда.", []string{"Правда? да, правда."}},
+
+		// prefix: после сокращения идёт имя, предложение не кончается
+		{"prefix", "Живёт на ул. Ленина. Потом ушёл.", []string{"Живёт на ул. Ленина.", "Потом ушёл."}},
+		{"english prefix", "Mr. Smith went home. He slept.", []string{"Mr. Smith went home.", "He slept."}},
+		{"dotted prefix", "We saw cities, e.g. Paris and Rome.", []string{"We saw cities, e.g. Paris and Rome."}},
+		{"spaced prefix", "Столица, т. е. Москва, велика.", []string{"Столица, т. е. Москва, велика."}},
+
+		// year: после числа — как nonTerminal, иначе — как prefix
+		{"year after number", "Это было в 1990 г. Потом всё изменилось.", []string{"Это было в 1990 г.", "Потом всё изменилось."}},
+		{"year before lowercase", "В 1990 г. в городе было тихо.", []string{"В 1990 г. в городе было тихо."}},
+		{"year as prefix", "Он приехал в г. Москва летом.", []string{"Он приехал в г. Москва летом."}},
+
+		// nonTerminal: конец предложения только перед заглавной буквой
+		{"non-terminal before capital", "Длина 5 см. Потом измерили ширину.", []string{"Длина 5 см.", "Потом измерили ширину."}},
+		{"non-terminal before number", "См. рис. 3 ниже.", []string{"См. рис. 3 ниже."}},
+		{"non-terminal at paragraph end", "Смотри рис.\n\nДалее текст.", []string{"Смотри рис.", "Далее текст."}},
+
+		// terminal: сокращение может закрыть предложение
+		{"terminal", "Купили хлеб, молоко и т.д. Потом ушли.", []string{"Купили хлеб, молоко и т.д.", "Потом ушли."}},
+		{"terminal before lowercase", "Хлеб, молоко и т.д. и т.п. были дома.", []string{"Хлеб, молоко и т.д. и т.п. были дома."}},
+		{"terminal english", "We bought apples, pears etc. Then we left.", []string{"We bought apples, pears etc.", "Then we left."}},
+		{"terminal currency", "Стоит 5 руб. Дальше дешевле.", []string{"Стоит 5 руб.", "Дальше дешевле."}},
+
+		// «т. д.» и «т. п.» через пробел — те же сокращения, что слитные
+		{"spaced т. д.", "Купили хлеб и т. д. Потом ушли.", []string{"Купили хлеб и т. д.", "Потом ушли."}},
+		{"spaced т. п.", "Книги, газеты и т. п. Всё сгорело.", []string{"Книги, газеты и т. п.", "Всё сгорело."}},
+		{"spaced т. д. before lowercase", "Хлеб и т. д. лежали на столе.", []string{"Хлеб и т. д. лежали на столе."}},
+		{"spaced т. д. at paragraph end", "Книги, газеты и т. д.\n\nНовый абзац.", []string{"Книги, газеты и т. д.", "Новый абзац."}},
+
+		{"initials", "А. С. Пушкин родился в Москве. Он поэт.", []string{"А. С. Пушкин родился в Москве.", "Он поэт."}},
+		{"closing quote", "Он сказал: «Иди.» Она ушла.", []string{"Он сказал: «Иди.»", "Она ушла."}},
+	}
+	s := NewSegmenter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Split(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Split(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package vectors
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloat32ToHalf(t *testing.T) {
+	pow2 := func(e int) float32 { return float32(math.Ldexp(1, e)) }
+	tests := []struct {
+		name string
+		in   float32
+		want uint16
+	}{
+		{"zero", 0, 0x0000},
+		{"negative zero", float32(math.Copysign(0, -1)), 0x8000},
+		{"one", 1, 0x3c00},
+		{"minus two", -2, 0xc000},
+		{"max half", 65504, 0x7bff},
+		{"below overflow midpoint", 65519, 0x7bff},
+		{"overflow midpoint rounds to inf", 65520, 0x7c00},
+		{"overflow", 1e6, 0x7c00},
+		{"negative overflow", -1e6, 0xfc00},
+		{"inf", float32(math.Inf(1)), 0x7c00},
+		{"negative inf", float32(math.Inf(-1)), 0xfc00},
+		{"nan", float32(math.NaN()), 0x7e00},
+		{"min normal", pow2(-14), 0x0400},
+		{"max subnormal", 1023 * pow2(-24), 0x03ff},
+		{"min subnormal", pow2(-24), 0x0001},
+		{"negative min subnormal", -pow2(-24), 0x8001},
+		{"half of min subnormal ties to zero", pow2(-25), 0x0000},
+		{"above half of min subnormal", 1.5 * pow2(-25), 0x0001},
+		{"underflow", pow2(-26), 0x0000},
+		{"subnormal tie to even down", 2.5 * pow2(-24), 0x0002},
+		{"subnormal tie to even up", 1.5 * pow2(-24), 0x0002},
+		{"normal tie to even down", 1 + pow2(-11), 0x3c00},
+		{"normal tie to even up", 1 + 3*pow2(-11), 0x3c02},
+		{"normal above tie", 1 + pow2(-11) + pow2(-20), 0x3c01},
+		{"rounding carries into exponent", 2 - pow2(-12), 0x4000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := float32ToHalf(tt.in); got != tt.want {
+				t.Fatalf("float32ToHalf(%g) = %#04x, want %#04x", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// Каждое значение half precision переводится во float32 и обратно без
+// потерь; NaN остаётся NaN.
+func TestHalfRoundTrip(t *testing.T) {
+	for h := 0; h <= 0xffff; h++ {
+		f := halfToFloat32(uint16(h))
+		if h&0x7c00 == 0x7c00 && h&0x3ff != 0 {
+			if !math.IsNaN(float64(f)) {
+				t.Fatalf("halfToFloat32(%#04x) = %g, want NaN", h, f)
+			}
+			continue
+		}
+		if got := float32ToHalf(f); got != uint16(h) {
+			t.Fatalf("float32ToHalf(halfToFloat32(%#04x) = %g) = %#04x", h, f, got)
+		}
+	}
+	if got := halfToFloat32(0x7bff); got != 65504 {
+		t.Fatalf("halfToFloat32(0x7bff) = %g, want 65504", got)
+	}
+	if got := halfToFloat32(0x0001); got != float32(math.Ldexp(1, -24)) {
+		t.Fatalf("halfToFloat32(0x0001) = %g, want 2^-24", got)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package vectors
+
+import (
+	"fmt"
+	"os"
+)
+
+// loadNative читает файл .t2g целиком: отображение в память
+// поддерживается только на unix-системах.
+func loadNative(path string) (*Embeddings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vectors: %v", err)
+	}
+	e, err := parseNative(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return e, nil
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package vectors
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// loadNative отображает файл .t2g в память. Отображение частное и
+// доступно для записи: изменения (например, нормировка) остаются
+// в памяти процесса и не попадают в файл.
+func loadNative(path string) (*Embeddings, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vectors: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, fmt.Errorf("%s: empty file", path)
+	}
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map %s: %v", path, err)
+	}
+	e, err := parseNative(data)
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return e, nil
+}
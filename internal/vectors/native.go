@@ -0,0 +1,156 @@
+package vectors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unsafe"
+)
+
+// Собственный формат text2glove (.t2g) рассчитан на отображение в память:
+//
+//	magic      [8]byte  "T2GVEC01"
+//	dtype      uint32   1 — float32, 2 — float16
+//	flags      uint32   бит 0 — векторы нормированы
+//	count      uint64   число слов
+//	dim        uint64   размерность
+//	wordsOff   uint64   смещение списка слов (слова через '\n')
+//	wordsSize  uint64   размер списка слов в байтах
+//	vectorsOff uint64   смещение векторов, кратно 64
+//
+// Векторы лежат подряд в little-endian, поэтому float32 на little-endian
+// машинах используются прямо из отображённого файла, без копирования.
+const (
+	nativeMagic      = "T2GVEC01"
+	nativeHeaderSize = 8 + 4 + 4 + 8*5
+	nativeAlign      = 64
+
+	dtypeFloat32 = 1
+	dtypeFloat16 = 2
+
+	flagNormalized = 1
+)
+
+type nativeHeader struct {
+	DType      uint32
+	Flags      uint32
+	Count      uint64
+	Dim        uint64
+	WordsOff   uint64
+	WordsSize  uint64
+	VectorsOff uint64
+}
+
+// writeNative пишет векторы в формате .t2g.
+func writeNative(w io.Writer, e *Embeddings, float16, normalized bool) error {
+	var words bytes.Buffer
+	for _, word := range e.Words {
+		words.WriteString(word)
+		words.WriteByte('\n')
+	}
+
+	h := nativeHeader{
+		DType:     dtypeFloat32,
+		Count:     uint64(len(e.Words)),
+		Dim:       uint64(e.Dim),
+		WordsOff:  nativeHeaderSize,
+		WordsSize: uint64(words.Len()),
+	}
+	if float16 {
+		h.DType = dtypeFloat16
+	}
+	if normalized {
+		h.Flags |= flagNormalized
+	}
+	h.VectorsOff = (h.WordsOff + h.WordsSize + nativeAlign - 1) / nativeAlign * nativeAlign
+
+	if _, err := io.WriteString(w, nativeMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, h); err != nil {
+		return err
+	}
+	padding := make([]byte, h.VectorsOff-h.WordsOff-h.WordsSize)
+	if _, err := w.Write(append(words.Bytes(), padding...)); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, 4*e.Dim)
+	for i := range e.Words {
+		buf = buf[:0]
+		for _, v := range e.Vector(i) {
+			if float16 {
+				buf = binary.LittleEndian.AppendUint16(buf, float32ToHalf(v))
+			} else {
+				buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+			}
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseNative разбирает содержимое файла .t2g. Значения float32 на
+// little-endian машинах не копируются: срез указывает прямо в data.
+func parseNative(data []byte) (*Embeddings, error) {
+	if len(data) < nativeHeaderSize || string(data[:8]) != nativeMagic {
+		return nil, fmt.Errorf("not a text2glove vectors file")
+	}
+	var h nativeHeader
+	if err := binary.Read(bytes.NewReader(data[8:nativeHeaderSize]), binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	width := uint64(4)
+	if h.DType == dtypeFloat16 {
+		width = 2
+	} else if h.DType != dtypeFloat32 {
+		return nil, fmt.Errorf("unknown value type %d", h.DType)
+	}
+	if h.WordsOff+h.WordsSize > uint64(len(data)) || h.VectorsOff+h.Count*h.Dim*width > uint64(len(data)) {
+		return nil, fmt.Errorf("truncated file")
+	}
+
+	words := string(data[h.WordsOff : h.WordsOff+h.WordsSize])
+	e := &Embeddings{
+		Dim:        int(h.Dim),
+		Words:      make([]string, 0, h.Count),
+		normalized: h.Flags&flagNormalized != 0,
+	}
+	for len(words) > 0 {
+		word, rest, _ := strings.Cut(words, "\n")
+		e.Words = append(e.Words, word)
+		words = rest
+	}
+	if uint64(len(e.Words)) != h.Count {
+		return nil, fmt.Errorf("expected %d words, found %d", h.Count, len(e.Words))
+	}
+
+	n := int(h.Count * h.Dim)
+	raw := data[h.VectorsOff : h.VectorsOff+uint64(n)*width]
+	switch {
+	case n == 0:
+	case h.DType == dtypeFloat32 && littleEndian:
+		e.Vectors = unsafe.Slice((*float32)(unsafe.Pointer(&raw[0])), n)
+	case h.DType == dtypeFloat32:
+		e.Vectors = make([]float32, n)
+		for i := range e.Vectors {
+			e.Vectors[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+		}
+	default:
+		e.Vectors = make([]float32, n)
+		for i := range e.Vectors {
+			e.Vectors[i] = halfToFloat32(binary.LittleEndian.Uint16(raw[2*i:]))
+		}
+	}
+	return e, nil
+}
+
+var littleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
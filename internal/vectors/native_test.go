@@ -0,0 +1,100 @@
+package vectors
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Файл .t2g, записанный Write, загружается через отображение в память с
+// теми же словами и значениями (float16 — с округлением до half).
+func TestNativeRoundTrip(t *testing.T) {
+	words := []string{"мир", "дом", "</s>", "word"}
+	values := []float32{
+		0.5, -1.25, 3,
+		1e-3, 65504, -0,
+		0.1, 0.2, 0.3,
+		-7, 1e-7, 42,
+	}
+	tests := []struct {
+		name      string
+		float16   bool
+		normalize bool
+	}{
+		{"float32", false, false},
+		{"float16", true, false},
+		{"normalized", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(append([]string(nil), words...), 3, append([]float32(nil), values...))
+			if tt.normalize {
+				e.Normalize()
+			}
+			want := append([]float32(nil), e.Vectors...)
+			if tt.float16 {
+				for i, v := range want {
+					want[i] = halfToFloat32(float32ToHalf(v))
+				}
+			}
+
+			path := filepath.Join(t.TempDir(), "vectors.t2g")
+			file, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := Write(file, e, FormatNative, WriteOptions{Float16: tt.float16}); err != nil {
+				t.Fatal(err)
+			}
+			if err := file.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Load(path, FormatAuto, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Words, words) || got.Dim != 3 {
+				t.Fatalf("loaded words %q, dim %d", got.Words, got.Dim)
+			}
+			if !reflect.DeepEqual(got.Vectors, want) {
+				t.Fatalf("loaded vectors %v, want %v", got.Vectors, want)
+			}
+			if got.Normalized() != tt.normalize {
+				t.Fatalf("Normalized() = %v, want %v", got.Normalized(), tt.normalize)
+			}
+			if i, ok := got.Lookup("</s>"); !ok || i != 2 {
+				t.Fatalf("Lookup(</s>) = %d, %v", i, ok)
+			}
+		})
+	}
+}
+
+func TestParseNativeRejectsBrokenFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vectors.t2g")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(file, New([]string{"a", "b"}, 2, []float32{1, 2, 3, 4}), FormatNative, WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseNative(data[:len(data)-1]); err == nil {
+		t.Fatal("parseNative accepted a truncated file")
+	}
+	if _, err := parseNative(append([]byte("T2GVEC00"), data[8:]...)); err == nil {
+		t.Fatal("parseNative accepted a wrong signature")
+	}
+	broken := append([]byte(nil), data...)
+	broken[8] = 7 // dtype
+	if _, err := parseNative(broken); err == nil {
+		t.Fatal("parseNative accepted an unknown value type")
+	}
+}
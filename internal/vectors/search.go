@@ -0,0 +1,167 @@
+package vectors
+
+import (
+	"container/heap"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Neighbor — слово и его косинусная близость к запросу.
+type Neighbor struct {
+	Word  string
+	Index int
+	Score float32
+}
+
+// minChunk — наименьшая порция слов для одной горутины.
+const minChunk = 4096
+
+// Nearest возвращает k слов с наибольшим скалярным произведением
+// с query, пропуская номера из exclude. Для нормированных векторов это
+// косинусная близость. Словарь делится на части, которые просматриваются
+// параллельно; каждая горутина держит свою кучу из k лучших.
+func (e *Embeddings) Nearest(query []float32, k int, exclude map[int]bool) []Neighbor {
+	n := len(e.Words)
+	if k <= 0 || n == 0 {
+		return nil
+	}
+
+	workers := min(runtime.NumCPU(), (n+minChunk-1)/minChunk)
+	chunk := (n + workers - 1) / workers
+	results := make([]neighborHeap, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			h := make(neighborHeap, 0, k+1)
+			end := min((w+1)*chunk, n)
+			for i := w * chunk; i < end; i++ {
+				if exclude[i] {
+					continue
+				}
+				score := Dot(query, e.Vectors[i*e.Dim:(i+1)*e.Dim])
+				if len(h) < k {
+					heap.Push(&h, Neighbor{Index: i, Score: score})
+				} else if score > h[0].Score {
+					h[0] = Neighbor{Index: i, Score: score}
+					heap.Fix(&h, 0)
+				}
+			}
+			results[w] = h
+		}(w)
+	}
+	wg.Wait()
+
+	var all []Neighbor
+	for _, h := range results {
+		all = append(all, h...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Score != all[j].Score {
+			return all[i].Score > all[j].Score
+		}
+		return all[i].Index < all[j].Index
+	})
+	if len(all) > k {
+		all = all[:k]
+	}
+	for i := range all {
+		all[i].Word = e.Words[all[i].Index]
+	}
+	return all
+}
+
+// Dot — скалярное произведение векторов одной длины.
+func Dot(a, b []float32) float32 {
+	var s0, s1, s2, s3 float32
+	b = b[:len(a)]
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// Analogy строит нормированный вектор b - a + c для аналогии
+// «a относится к b, как c к ?».
+func (e *Embeddings) Analogy(a, b, c int) []float32 {
+	query := make([]float32, e.Dim)
+	va, vb, vc := e.Vector(a), e.Vector(b), e.Vector(c)
+	for j := range query {
+		query[j] = vb[j] - va[j] + vc[j]
+	}
+	normalize(query)
+	return query
+}
+
+// neighborHeap — куча с наименьшей близостью в вершине.
+type neighborHeap []Neighbor
+
+func (h neighborHeap) Len() int { return len(h) }
+func (h neighborHeap) Less(i, j int) bool {
+	if h[i].Score != h[j].Score {
+		return h[i].Score < h[j].Score
+	}
+	return h[i].Index > h[j].Index
+}
+func (h neighborHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *neighborHeap) Push(x interface{}) { *h = append(*h, x.(Neighbor)) }
+func (h *neighborHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// Query разбирает запрос — слово или выражение вида
+// «король - мужчина + женщина» — и возвращает нормированный вектор
+// запроса и номера слов запроса, которые нужно исключить из ответа.
+// Знаки отделяются пробелами или стоят перед словом («-мужчина»),
+// поэтому слова с дефисом («северо-запад») не разбиваются.
+func (e *Embeddings) Query(expr string) ([]float32, map[int]bool, error) {
+	query := make([]float32, e.Dim)
+	exclude := make(map[int]bool)
+	sign := float32(1)
+	expectWord := true
+	for _, token := range strings.Fields(expr) {
+		for token != "" && (token[0] == '+' || token[0] == '-') {
+			if token[0] == '-' {
+				sign = -sign
+			}
+			token = token[1:]
+			expectWord = true
+		}
+		if token == "" {
+			continue
+		}
+		if !expectWord {
+			return nil, nil, fmt.Errorf("expected + or - before %q", token)
+		}
+		i, ok := e.Lookup(token)
+		if !ok {
+			return nil, nil, fmt.Errorf("word %q is not in the vocabulary", token)
+		}
+		for j, x := range e.Vector(i) {
+			query[j] += sign * x
+		}
+		exclude[i] = true
+		sign = 1
+		expectWord = false
+	}
+	if len(exclude) == 0 || expectWord {
+		return nil, nil, fmt.Errorf("incomplete query %q", expr)
+	}
+	normalize(query)
+	return query, exclude, nil
+}
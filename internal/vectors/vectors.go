@@ -0,0 +1,360 @@
+package vectors
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/terratensor/text2glove/internal/vocab"
+)
+
+// Format — формат файла векторов.
+type Format string
+
+const (
+	FormatAuto        Format = "auto"         // по расширению и содержимому
+	FormatGloVe       Format = "glove"        // текст: слово и компоненты, без заголовка
+	FormatWord2Vec    Format = "word2vec"     // текст с заголовком «число_слов размерность»
+	FormatWord2VecBin Format = "word2vec-bin" // двоичный word2vec: заголовок, слово, float32
+	FormatGloVeBin    Format = "glove-bin"    // двоичный вывод train: все параметры как float64
+	FormatFastText    Format = "fasttext"     // .vec fastText: тот же текст с заголовком, что word2vec
+	FormatNative      Format = "t2g"          // собственный формат для отображения в память
+)
+
+// ParseFormat проверяет имя формата.
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(s); format {
+	case FormatAuto, FormatGloVe, FormatWord2Vec, FormatWord2VecBin, FormatGloVeBin, FormatFastText, FormatNative:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown vectors format %q: expected auto|glove|word2vec|word2vec-bin|glove-bin|fasttext|t2g", s)
+}
+
+// Embeddings — векторы слов в одном непрерывном массиве.
+type Embeddings struct {
+	Words   []string
+	Dim     int
+	Vectors []float32 // len(Words)*Dim значений подряд
+	index   map[string]int
+
+	normalized bool // векторы уже единичной длины
+}
+
+// New создаёт набор векторов из слов и непрерывного массива значений.
+func New(words []string, dim int, values []float32) *Embeddings {
+	e := &Embeddings{Words: words, Dim: dim, Vectors: values}
+	e.buildIndex()
+	return e
+}
+
+func (e *Embeddings) buildIndex() {
+	e.index = make(map[string]int, len(e.Words))
+	for i, word := range e.Words {
+		if _, ok := e.index[word]; !ok {
+			e.index[word] = i
+		}
+	}
+}
+
+// Len возвращает число слов.
+func (e *Embeddings) Len() int {
+	return len(e.Words)
+}
+
+// Lookup возвращает номер слова.
+func (e *Embeddings) Lookup(word string) (int, bool) {
+	i, ok := e.index[word]
+	return i, ok
+}
+
+// Vector возвращает вектор слова с номером i (без копирования).
+func (e *Embeddings) Vector(i int) []float32 {
+	return e.Vectors[i*e.Dim : (i+1)*e.Dim]
+}
+
+// Normalize приводит все векторы к единичной длине; нулевые остаются нулевыми.
+func (e *Embeddings) Normalize() {
+	if e.normalized {
+		return
+	}
+	for i := range e.Words {
+		normalize(e.Vector(i))
+	}
+	e.normalized = true
+}
+
+// Normalized сообщает, приведены ли векторы к единичной длине.
+func (e *Embeddings) Normalized() bool {
+	return e.normalized
+}
+
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for j := range v {
+		v[j] *= inv
+	}
+}
+
+// Restrict оставляет только первые n слов (в файлах GloVe и word2vec
+// слова идут по убыванию частоты).
+func (e *Embeddings) Restrict(n int) {
+	if n <= 0 || n >= len(e.Words) {
+		return
+	}
+	e.Words = e.Words[:n]
+	e.Vectors = e.Vectors[:n*e.Dim]
+	e.buildIndex()
+}
+
+// Load читает векторы из файла. Для FormatGloVeBin нужен словарь,
+// по которому обучалась модель (vocabPath).
+func Load(path string, format Format, vocabPath string) (*Embeddings, error) {
+	if format == FormatAuto || format == "" {
+		var err error
+		if format, err = detectFormat(path, vocabPath); err != nil {
+			return nil, err
+		}
+	}
+	if format == FormatNative {
+		e, err := loadNative(path)
+		if err != nil {
+			return nil, err
+		}
+		e.buildIndex()
+		return e, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vectors: %v", err)
+	}
+	defer file.Close()
+	r := bufio.NewReaderSize(file, 1024*1024)
+
+	var e *Embeddings
+	switch format {
+	case FormatGloVe, FormatWord2Vec, FormatFastText:
+		e, err = readText(r)
+	case FormatWord2VecBin:
+		e, err = readWord2VecBinary(r)
+	case FormatGloVeBin:
+		e, err = readGloVeBinary(file, r, vocabPath)
+	default:
+		err = fmt.Errorf("unsupported vectors format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	e.buildIndex()
+	return e, nil
+}
+
+// detectFormat различает текстовые и двоичные файлы; файл .t2g узнаётся
+// по сигнатуре, двоичный файл
+// со словарём считается выводом train, без словаря — word2vec.
+func detectFormat(path, vocabPath string) (Format, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open vectors: %v", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 4096)
+	n, _ := io.ReadFull(file, head)
+	head = head[:n]
+	if strings.HasPrefix(string(head), nativeMagic) {
+		return FormatNative, nil
+	}
+	if line, _, ok := strings.Cut(string(head), "\n"); ok {
+		if _, _, isHeader := parseHeader(line); isHeader {
+			// Заголовок есть и у текстового, и у двоичного word2vec
+			if strings.HasSuffix(strings.ToLower(path), ".bin") {
+				return FormatWord2VecBin, nil
+			}
+			return FormatWord2Vec, nil
+		}
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".bin") {
+		if vocabPath == "" {
+			return "", fmt.Errorf("%s looks like a binary GloVe file: pass the vocabulary file", path)
+		}
+		return FormatGloVeBin, nil
+	}
+	return FormatGloVe, nil
+}
+
+// parseHeader разбирает строку «число_слов размерность».
+func parseHeader(line string) (count, dim int, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+	count, err1 := strconv.Atoi(fields[0])
+	dim, err2 := strconv.Atoi(fields[1])
+	return count, dim, err1 == nil && err2 == nil && dim > 0
+}
+
+// readText читает текстовые векторы GloVe или word2vec (с заголовком).
+func readText(r *bufio.Reader) (*Embeddings, error) {
+	e := &Embeddings{}
+	for lineNum := 1; ; lineNum++ {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if text := strings.TrimRight(line, "\r\n"); text != "" {
+			if perr := e.parseTextLine(text, lineNum); perr != nil {
+				return nil, perr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	if len(e.Words) == 0 {
+		return nil, fmt.Errorf("no vectors found")
+	}
+	return e, nil
+}
+
+func (e *Embeddings) parseTextLine(text string, lineNum int) error {
+	if lineNum == 1 {
+		if count, dim, ok := parseHeader(text); ok {
+			e.Dim = dim
+			e.Words = make([]string, 0, count)
+			e.Vectors = make([]float32, 0, count*dim)
+			return nil
+		}
+	}
+	fields := strings.Fields(text)
+	if e.Dim == 0 {
+		e.Dim = len(fields) - 1
+	}
+	if len(fields) != e.Dim+1 || e.Dim == 0 {
+		return fmt.Errorf("line %d: expected word and %d values", lineNum, e.Dim)
+	}
+	for _, field := range fields[1:] {
+		v, err := strconv.ParseFloat(field, 32)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", lineNum, err)
+		}
+		e.Vectors = append(e.Vectors, float32(v))
+	}
+	e.Words = append(e.Words, fields[0])
+	return nil
+}
+
+// readWord2VecBinary читает двоичный формат word2vec: заголовок, затем
+// для каждого слова — слово, пробел и Dim значений float32.
+func readWord2VecBinary(r *bufio.Reader) (*Embeddings, error) {
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+	count, dim, ok := parseHeader(header)
+	if !ok {
+		return nil, fmt.Errorf("invalid word2vec header %q", strings.TrimSpace(header))
+	}
+
+	e := &Embeddings{
+		Dim:     dim,
+		Words:   make([]string, 0, count),
+		Vectors: make([]float32, count*dim),
+	}
+	buf := make([]byte, 4*dim)
+	for i := 0; i < count; i++ {
+		word, err := r.ReadString(' ')
+		if err != nil {
+			return nil, fmt.Errorf("word %d: %v", i+1, err)
+		}
+		e.Words = append(e.Words, strings.TrimLeft(strings.TrimSuffix(word, " "), "\n"))
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, fmt.Errorf("word %d: %v", i+1, err)
+		}
+		v := e.Vectors[i*dim : (i+1)*dim]
+		for j := range v {
+			v[j] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*j:]))
+		}
+	}
+	return e, nil
+}
+
+// readGloVeBinary читает двоичный вывод train: 2·V векторов длины D+1
+// (слова, затем контексты, последняя компонента — смещение). Вектор
+// слова — сумма векторов слова и контекста без смещения, как -model 2.
+func readGloVeBinary(file *os.File, r *bufio.Reader, vocabPath string) (*Embeddings, error) {
+	entries, err := vocab.Read(vocabPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	vocabSize := int64(len(entries))
+	values := info.Size() / 8
+	if vocabSize == 0 || values%(2*vocabSize) != 0 || values/(2*vocabSize) < 2 {
+		return nil, fmt.Errorf("file size does not match a vocabulary of %d words", vocabSize)
+	}
+	stride := int(values / (2 * vocabSize))
+	dim := stride - 1
+
+	e := &Embeddings{
+		Dim:     dim,
+		Words:   make([]string, len(entries)),
+		Vectors: make([]float32, len(entries)*dim),
+	}
+	for i, entry := range entries {
+		e.Words[i] = entry.Word
+	}
+
+	row := make([]byte, 8*stride)
+	words := make([]float64, len(entries)*dim)
+	for half := 0; half < 2; half++ {
+		for i := range entries {
+			if _, err := io.ReadFull(r, row); err != nil {
+				return nil, err
+			}
+			for j := 0; j < dim; j++ {
+				v := math.Float64frombits(binary.LittleEndian.Uint64(row[8*j:]))
+				if half == 0 {
+					words[i*dim+j] = v
+				} else {
+					e.Vectors[i*dim+j] = float32(words[i*dim+j] + v)
+				}
+			}
+		}
+	}
+	return e, nil
+}
+
+// Keep оставляет только слова, для которых keep возвращает true,
+// сохраняя их порядок.
+func (e *Embeddings) Keep(keep func(word string) bool) {
+	n := 0
+	for i, word := range e.Words {
+		if !keep(word) {
+			continue
+		}
+		if n != i {
+			e.Words[n] = word
+			copy(e.Vectors[n*e.Dim:(n+1)*e.Dim], e.Vector(i))
+		}
+		n++
+	}
+	e.Words = e.Words[:n]
+	e.Vectors = e.Vectors[:n*e.Dim]
+	e.buildIndex()
+}
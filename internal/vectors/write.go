@@ -0,0 +1,81 @@
+package vectors
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// WriteOptions задаёт параметры записи векторов.
+type WriteOptions struct {
+	Float16 bool // значения в half precision (только FormatNative)
+}
+
+// Write пишет векторы в заданном формате. Текстовые форматы пишутся
+// с шестью знаками после запятой, как в GloVe и word2vec.
+func Write(w io.Writer, e *Embeddings, format Format, opts WriteOptions) error {
+	if opts.Float16 && format != FormatNative {
+		return fmt.Errorf("float16 values are supported only by the %s format", FormatNative)
+	}
+
+	bw := bufio.NewWriterSize(w, 1024*1024)
+	var err error
+	switch format {
+	case FormatGloVe:
+		err = writeText(bw, e, false)
+	case FormatWord2Vec, FormatFastText:
+		err = writeText(bw, e, true)
+	case FormatWord2VecBin:
+		err = writeWord2VecBinary(bw, e)
+	case FormatNative:
+		err = writeNative(bw, e, opts.Float16, e.normalized)
+	default:
+		err = fmt.Errorf("cannot write vectors in %q format", format)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeText(w *bufio.Writer, e *Embeddings, header bool) error {
+	if header {
+		fmt.Fprintf(w, "%d %d\n", e.Len(), e.Dim)
+	}
+	buf := make([]byte, 0, 16*e.Dim)
+	for i, word := range e.Words {
+		buf = append(buf[:0], word...)
+		for _, v := range e.Vector(i) {
+			buf = append(buf, ' ')
+			buf = strconv.AppendFloat(buf, float64(v), 'f', 6, 32)
+		}
+		buf = append(buf, '\n')
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeWord2VecBinary пишет формат word2vec -binary 1: заголовок, затем
+// слово, пробел, Dim значений float32 и перевод строки.
+func writeWord2VecBinary(w *bufio.Writer, e *Embeddings) error {
+	fmt.Fprintf(w, "%d %d\n", e.Len(), e.Dim)
+	buf := make([]byte, 0, 4*e.Dim)
+	for i, word := range e.Words {
+		w.WriteString(word)
+		w.WriteByte(' ')
+		buf = buf[:0]
+		for _, v := range e.Vector(i) {
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(v))
+		}
+		w.Write(buf)
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,583 @@
+package vocab
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"container/heap"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	blockSize = 4 * 1024 * 1024 // 4MB — размер порции корпуса для рабочего
+
+	// DefaultMemoryWords — сколько различных слов держать в памяти
+	// всеми рабочими вместе, прежде чем сбрасывать счётчики на диск
+	DefaultMemoryWords = 10_000_000
+
+	// unknownToken зарезервирован GloVe для слов вне словаря
+	unknownToken = "<unk>"
+)
+
+// Entry — слово и его частота.
+type Entry struct {
+	Word  string
+	Count uint64
+}
+
+// Options задаёт параметры подсчёта.
+type Options struct {
+	Workers     int    // число рабочих
+	MinCount    uint64 // не выводить слова с частотой меньше MinCount
+	MaxVocab    int    // ограничить словарь MaxVocab самыми частыми словами (0 — без ограничения)
+	MemoryWords int    // предел числа различных слов в памяти (0 — DefaultMemoryWords)
+	TempDir     string // каталог для временных файлов (пусто — системный)
+}
+
+// Stats — итоги подсчёта.
+type Stats struct {
+	Tokens  uint64 // всего токенов в корпусе
+	Unique  uint64 // различных слов
+	Unknown uint64 // пропущенных токенов <unk>
+	Spills  int    // сколько раз счётчики сбрасывались на диск
+}
+
+// Count считает частоты слов в корпусах paths ("-" — стандартный ввод,
+// файлы .gz распаковываются) и возвращает словарь в порядке, который
+// ожидает GloVe: по убыванию частоты, при равенстве — по словам.
+//
+// Каждый рабочий считает свою порцию в собственной таблице; при превышении
+// предела памяти таблица сортируется и сбрасывается во временный файл.
+// Затем все сброшенные части сливаются k-путевым слиянием (не больше
+// mergeFanIn частей за раз), так что в памяти одновременно находятся только
+// счётчики рабочих и итоговый словарь (не более MaxVocab слов, если
+// ограничение задано).
+func Count(paths []string, opts Options) ([]Entry, Stats, error) {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.MemoryWords <= 0 {
+		opts.MemoryWords = DefaultMemoryWords
+	}
+
+	tempDir, err := os.MkdirTemp(opts.TempDir, "text2glove-vocab-")
+	if err != nil {
+		return nil, Stats{}, fmt.Errorf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var stats Stats
+	var tokens, unknown atomic.Uint64
+
+	blocks := make(chan []byte, opts.Workers*2)
+	workers := make([]*counter, opts.Workers)
+	errs := make(chan error, opts.Workers+1)
+
+	var wg sync.WaitGroup
+	for i := range workers {
+		workers[i] = &counter{
+			id:      i,
+			limit:   opts.MemoryWords/opts.Workers + 1,
+			tempDir: tempDir,
+			words:   make(map[string]uint64),
+			tokens:  &tokens,
+			unknown: &unknown,
+		}
+		wg.Add(1)
+		go func(c *counter) {
+			defer wg.Done()
+			for block := range blocks {
+				if err := c.countBlock(block); err != nil {
+					errs <- err
+					// Дочитываем канал, чтобы не блокировать чтение корпуса
+					for range blocks {
+					}
+					return
+				}
+			}
+		}(workers[i])
+	}
+
+	readErr := readBlocks(paths, blocks)
+	close(blocks)
+	wg.Wait()
+	if readErr != nil {
+		return nil, stats, readErr
+	}
+	select {
+	case err := <-errs:
+		return nil, stats, err
+	default:
+	}
+
+	// Сбрасываем остатки и сливаем все части
+	var spills []string
+	for _, c := range workers {
+		if err := c.spill(); err != nil {
+			return nil, stats, err
+		}
+		spills = append(spills, c.spills...)
+		stats.Spills += c.forced
+	}
+
+	entries, unique, err := merge(spills, opts, tempDir)
+	if err != nil {
+		return nil, stats, err
+	}
+
+	stats.Tokens = tokens.Load()
+	stats.Unknown = unknown.Load()
+	stats.Unique = unique
+	return entries, stats, nil
+}
+
+// Write записывает словарь в формате GloVe: «слово частота» в строке.
+func Write(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		bw.WriteString(e.Word)
+		bw.WriteByte(' ')
+		bw.WriteString(strconv.FormatUint(e.Count, 10))
+		if err := bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Read читает словарь в формате GloVe.
+func Read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vocabulary: %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"word count\"", path, lineNum)
+		}
+		count, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid count: %v", path, lineNum, err)
+		}
+		entries = append(entries, Entry{Word: fields[0], Count: count})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read vocabulary: %v", err)
+	}
+	return entries, nil
+}
+
+// counter — счётчик одного рабочего.
+type counter struct {
+	id      int
+	limit   int
+	tempDir string
+	words   map[string]uint64
+	spills  []string
+	forced  int // сбросы из-за предела памяти
+	tokens  *atomic.Uint64
+	unknown *atomic.Uint64
+}
+
+func (c *counter) countBlock(block []byte) error {
+	var tokens, unknown uint64
+	for len(block) > 0 {
+		start := bytes.IndexFunc(block, func(r rune) bool { return !isSpace(r) })
+		if start < 0 {
+			break
+		}
+		block = block[start:]
+		end := bytes.IndexFunc(block, isSpace)
+		if end < 0 {
+			end = len(block)
+		}
+		word := block[:end]
+		block = block[end:]
+		if bytes.IndexByte(word, '\r') >= 0 {
+			// get_word в GloVe пропускает '\r', не разделяя им слова
+			word = bytes.ReplaceAll(word, []byte{'\r'}, nil)
+			if len(word) == 0 {
+				continue
+			}
+		}
+
+		tokens++
+		if string(word) == unknownToken {
+			unknown++
+			continue
+		}
+		c.words[string(word)]++
+
+		if len(c.words) >= c.limit {
+			if err := c.spill(); err != nil {
+				return err
+			}
+			c.forced++
+		}
+	}
+	c.tokens.Add(tokens)
+	c.unknown.Add(unknown)
+	return nil
+}
+
+// spill сортирует таблицу и сбрасывает её во временный файл.
+func (c *counter) spill() error {
+	if len(c.words) == 0 {
+		return nil
+	}
+
+	path := filepath.Join(c.tempDir, fmt.Sprintf("counts-%03d-%05d.txt", c.id, len(c.spills)))
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %v", err)
+	}
+	defer file.Close()
+
+	words := make([]string, 0, len(c.words))
+	for word := range c.words {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+
+	w := bufio.NewWriterSize(file, 1024*1024)
+	for _, word := range words {
+		w.WriteString(word)
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatUint(c.words[word], 10))
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill file: %v", err)
+	}
+
+	c.spills = append(c.spills, path)
+	c.words = make(map[string]uint64)
+	return nil
+}
+
+// readBlocks читает корпуса порциями, разрезая их по пробельным символам.
+func readBlocks(paths []string, blocks chan<- []byte) error {
+	for _, path := range paths {
+		if err := readFileBlocks(path, blocks); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFileBlocks(path string, blocks chan<- []byte) error {
+	r, err := OpenCorpus(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var carry []byte
+	for {
+		buf := make([]byte, len(carry), len(carry)+blockSize)
+		copy(buf, carry)
+		n, err := io.ReadFull(r, buf[len(carry):cap(buf)])
+		buf = buf[:len(carry)+n]
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			if len(buf) > 0 {
+				blocks <- buf
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read corpus %s: %v", path, err)
+		}
+
+		// Не разрываем слово между порциями
+		cut := bytes.LastIndexAny(buf, " \t\n")
+		if cut < 0 {
+			carry = buf
+			continue
+		}
+		carry = buf[cut+1:]
+		blocks <- buf[:cut+1]
+	}
+}
+
+// OpenCorpus открывает корпус: "-" — стандартный ввод, файлы .gz распаковываются.
+func OpenCorpus(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %v", err)
+	}
+	if !strings.HasSuffix(strings.ToLower(path), ".gz") {
+		return file, nil
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("gzip error: %v", err)
+	}
+	return &gzipCorpus{Reader: gz, file: file}, nil
+}
+
+type gzipCorpus struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipCorpus) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n'
+}
+
+// mergeFanIn — сколько частей сливается за раз. Частей может быть сколько
+// угодно, а открытых файлов — не больше этого числа: лишние части сначала
+// сливаются в промежуточные файлы.
+const mergeFanIn = 64
+
+// merge сливает отсортированные части, суммируя частоты одинаковых слов.
+func merge(paths []string, opts Options, tempDir string) ([]Entry, uint64, error) {
+	for round := 0; len(paths) > mergeFanIn; round++ {
+		var merged []string
+		for i := 0; i < len(paths); i += mergeFanIn {
+			group := paths[i:min(i+mergeFanIn, len(paths))]
+			path := filepath.Join(tempDir, fmt.Sprintf("merged-%02d-%05d.txt", round, len(merged)))
+			if err := mergeToFile(group, path); err != nil {
+				return nil, 0, err
+			}
+			for _, p := range group {
+				os.Remove(p)
+			}
+			merged = append(merged, path)
+		}
+		paths = merged
+	}
+
+	top := newTopEntries(opts.MaxVocab)
+	var unique uint64
+	err := mergeSpills(paths, func(e Entry) error {
+		unique++
+		if e.Count >= opts.MinCount {
+			top.add(e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return top.sorted(), unique, nil
+}
+
+// mergeToFile сливает части paths в одну часть path того же формата.
+func mergeToFile(paths []string, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create spill file: %v", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriterSize(file, 1024*1024)
+	err = mergeSpills(paths, func(e Entry) error {
+		w.WriteString(e.Word)
+		w.WriteByte(' ')
+		w.WriteString(strconv.FormatUint(e.Count, 10))
+		return w.WriteByte('\n')
+	})
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write spill file: %v", err)
+	}
+	return nil
+}
+
+// mergeSpills сливает отсортированные части и передаёт emit каждое слово
+// с суммарной частотой, по возрастанию слов.
+func mergeSpills(paths []string, emit func(Entry) error) error {
+	readers := make([]*spillReader, 0, len(paths))
+	defer func() {
+		for _, r := range readers {
+			r.file.Close()
+		}
+	}()
+
+	h := &mergeHeap{}
+	for _, path := range paths {
+		r, err := openSpill(path)
+		if err != nil {
+			return err
+		}
+		readers = append(readers, r)
+		if ok, err := r.next(); err != nil {
+			return err
+		} else if ok {
+			heap.Push(h, r)
+		}
+	}
+
+	var current Entry
+	for h.Len() > 0 {
+		r := (*h)[0]
+		if r.entry.Word != current.Word && current.Count > 0 {
+			if err := emit(current); err != nil {
+				return err
+			}
+			current = Entry{}
+		}
+		current.Word = r.entry.Word
+		current.Count += r.entry.Count
+
+		ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+		} else {
+			heap.Pop(h)
+		}
+	}
+	if current.Count > 0 {
+		return emit(current)
+	}
+	return nil
+}
+
+type spillReader struct {
+	file    *os.File
+	scanner *bufio.Scanner
+	entry   Entry
+}
+
+func openSpill(path string) (*spillReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spill file: %v", err)
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	return &spillReader{file: file, scanner: scanner}, nil
+}
+
+func (r *spillReader) next() (bool, error) {
+	if !r.scanner.Scan() {
+		return false, r.scanner.Err()
+	}
+	line := r.scanner.Text()
+	sep := strings.LastIndexByte(line, ' ')
+	count, err := strconv.ParseUint(line[sep+1:], 10, 64)
+	if sep < 0 || err != nil {
+		return false, fmt.Errorf("corrupted spill file %s", r.file.Name())
+	}
+	r.entry = Entry{Word: line[:sep], Count: count}
+	return true, nil
+}
+
+type mergeHeap []*spillReader
+
+func (h mergeHeap) Len() int            { return len(h) }
+func (h mergeHeap) Less(i, j int) bool  { return h[i].entry.Word < h[j].entry.Word }
+func (h mergeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(*spillReader)) }
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// less задаёт порядок словаря GloVe: по убыванию частоты, затем по слову.
+func less(a, b Entry) bool {
+	if a.Count != b.Count {
+		return a.Count > b.Count
+	}
+	return compareSigned(a.Word, b.Word) < 0
+}
+
+// compareSigned сравнивает строки как scmp в vocab_count.c: байты считаются
+// знаковыми (char), поэтому многобайтовые символы UTF-8 идут раньше ASCII.
+// Так совпадает порядок слов с равной частотой, а значит и их номера.
+func compareSigned(a, b string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return int(int8(a[i])) - int(int8(b[i]))
+		}
+	}
+	// Одно слово — начало другого: scmp сравнивает следующий знаковый байт
+	// более длинного слова с завершающим нулём, поэтому слово, продолженное
+	// байтом ≥ 0x80, идёт раньше своего начала
+	switch {
+	case len(a) < len(b):
+		return -int(int8(b[len(a)]))
+	case len(a) > len(b):
+		return int(int8(a[len(b)]))
+	}
+	return 0
+}
+
+// topEntries хранит все слова или, при заданном пределе, limit самых частых
+// (мин-куча по порядку словаря).
+type topEntries struct {
+	limit   int
+	entries []Entry
+}
+
+func newTopEntries(limit int) *topEntries {
+	return &topEntries{limit: limit}
+}
+
+func (t *topEntries) Len() int           { return len(t.entries) }
+func (t *topEntries) Less(i, j int) bool { return less(t.entries[j], t.entries[i]) }
+func (t *topEntries) Swap(i, j int)      { t.entries[i], t.entries[j] = t.entries[j], t.entries[i] }
+func (t *topEntries) Push(x interface{}) { t.entries = append(t.entries, x.(Entry)) }
+func (t *topEntries) Pop() interface{} {
+	n := len(t.entries)
+	x := t.entries[n-1]
+	t.entries = t.entries[:n-1]
+	return x
+}
+
+func (t *topEntries) add(e Entry) {
+	if t.limit <= 0 {
+		t.entries = append(t.entries, e)
+		return
+	}
+	if len(t.entries) < t.limit {
+		heap.Push(t, e)
+		return
+	}
+	// Вершина кучи — наименее частое из сохранённых слов
+	if less(e, t.entries[0]) {
+		t.entries[0] = e
+		heap.Fix(t, 0)
+	}
+}
+
+func (t *topEntries) sorted() []Entry {
+	sort.Slice(t.entries, func(i, j int) bool { return less(t.entries[i], t.entries[j]) })
+	return t.entries
+}
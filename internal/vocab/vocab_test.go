@@ -0,0 +1,80 @@
+package vocab
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// При крошечном пределе памяти частей больше mergeFanIn, и слияние идёт
+// через промежуточные файлы; частоты должны совпасть с подсчётом в памяти.
+func TestCountManySpills(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var corpus strings.Builder
+	want := make(map[string]uint64)
+	for i := 0; i < 20000; i++ {
+		// Частоты слов убывают, как в реальном корпусе
+		word := fmt.Sprintf("w%d", int(rng.ExpFloat64()*300))
+		if i%7 == 0 {
+			word = "слово" + word
+		}
+		want[word]++
+		corpus.WriteString(word)
+		if i%13 == 0 {
+			corpus.WriteByte('\n')
+		} else {
+			corpus.WriteByte(' ')
+		}
+	}
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(path, []byte(corpus.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "all words", opts: Options{Workers: 2, MemoryWords: 4}},
+		{name: "min count and max vocab", opts: Options{Workers: 3, MemoryWords: 6, MinCount: 5, MaxVocab: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.TempDir = t.TempDir()
+			entries, stats, err := Count([]string{path}, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Spills <= mergeFanIn {
+				t.Fatalf("only %d spills, the test must exceed the merge fan-in %d", stats.Spills, mergeFanIn)
+			}
+			if stats.Tokens != 20000 || stats.Unique != uint64(len(want)) {
+				t.Fatalf("tokens %d, unique %d; want 20000, %d", stats.Tokens, stats.Unique, len(want))
+			}
+
+			var expected []Entry
+			for word, count := range want {
+				if count >= opts.MinCount {
+					expected = append(expected, Entry{Word: word, Count: count})
+				}
+			}
+			sort.Slice(expected, func(i, j int) bool { return less(expected[i], expected[j]) })
+			if opts.MaxVocab > 0 && len(expected) > opts.MaxVocab {
+				expected = expected[:opts.MaxVocab]
+			}
+			if len(entries) != len(expected) {
+				t.Fatalf("got %d entries, want %d", len(entries), len(expected))
+			}
+			for i := range expected {
+				if entries[i] != expected[i] {
+					t.Fatalf("entry %d: got %+v, want %+v", i, entries[i], expected[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package writer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Format — профиль выходного корпуса для конкретного инструмента обучения.
+type Format string
+
+const (
+	FormatGloVe        Format = "glove"        // строка на документ (или предложение)
+	FormatFastText     Format = "fasttext"     // fastText supervised: __label__метка текст
+	FormatWord2Vec     Format = "word2vec"     // предложение в строке, не длиннее MaxLineLength слов
+	FormatLineSentence Format = "linesentence" // gensim LineSentence: предложение в строке
+)
+
+// Предел числа слов в строке по умолчанию: MAX_SENTENCE_LENGTH в word2vec
+// и MAX_WORDS_IN_BATCH в gensim — более длинные строки они обрезают.
+const (
+	word2vecMaxLineLength     = 1000
+	lineSentenceMaxLineLength = 10000
+)
+
+// ParseFormat проверяет имя профиля из конфигурации.
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(s); format {
+	case FormatGloVe, FormatFastText, FormatWord2Vec, FormatLineSentence:
+		return format, nil
+	}
+	return "", fmt.Errorf("unknown output format %q: expected glove|fasttext|word2vec|linesentence", s)
+}
+
+// NeedsSentences сообщает, требует ли профиль деления на предложения.
+func (f Format) NeedsSentences() bool {
+	return f == FormatWord2Vec || f == FormatLineSentence
+}
+
+// Document — обработанный документ: исходный файл и строки текста
+// (весь документ в режиме document или предложения в режиме sentence).
+type Document struct {
+	Seq    int // номер входного файла в порядке обхода
+	Source string
+	Lines  []string
+	Failed bool      // файл не обработан: документ только занимает место в очереди
+	Meta   *Metadata // сведения об источнике для файла метаданных; nil — нет
+}
+
+// Options задаёт формат вывода: как документ превращается в строки.
+type Options struct {
+	Format        Format
+	Labels        map[string][]string // метки документов по имени файла (fasttext)
+	DefaultLabel  string              // метка документов без меток; пусто — такие документы пропускаются
+	MaxLineLength int                 // предел слов в строке; 0 — по умолчанию для профиля
+	Subsampler    *Subsampler         // nil — без прореживания частых слов
+}
+
+// LoadLabels читает метки документов: в каждой строке имя файла и через
+// табуляцию метки, разделённые пробелами или запятыми. Строки,
+// начинающиеся с #, пропускаются.
+func LoadLabels(path string) (map[string][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open labels file: %v", err)
+	}
+	defer file.Close()
+
+	labels := make(map[string][]string)
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, list, ok := strings.Cut(line, "\t")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected \"file<TAB>labels\"", path, lineNum)
+		}
+		fields := strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' })
+		labels[strings.TrimSpace(name)] = append(labels[strings.TrimSpace(name)], fields...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read labels file: %v", err)
+	}
+	return labels, nil
+}
+
+// render возвращает строки документа в формате профиля; ok == false,
+// если документ нужно пропустить.
+func (o Options) render(doc Document) (lines []string, ok bool) {
+	if o.Subsampler != nil {
+		sampled := make([]string, 0, len(doc.Lines))
+		for i, line := range doc.Lines {
+			if line = o.Subsampler.Apply(doc.Source, i, line); line != "" {
+				sampled = append(sampled, line)
+			}
+		}
+		doc.Lines = sampled
+	}
+
+	switch o.Format {
+	case FormatFastText:
+		labels := o.labels(doc.Source)
+		if len(labels) == 0 {
+			return nil, false
+		}
+		prefix := fastTextPrefix(labels)
+		for _, line := range doc.Lines {
+			lines = append(lines, prefix+line)
+		}
+		return lines, true
+
+	case FormatWord2Vec, FormatLineSentence:
+		limit := o.MaxLineLength
+		if limit <= 0 {
+			limit = word2vecMaxLineLength
+			if o.Format == FormatLineSentence {
+				limit = lineSentenceMaxLineLength
+			}
+		}
+		for _, line := range doc.Lines {
+			lines = append(lines, wrapWords(line, limit)...)
+		}
+		return lines, true
+	}
+	return doc.Lines, true
+}
+
+// labels ищет метки по имени файла, затем по имени без расширений.
+func (o Options) labels(source string) []string {
+	name := filepath.Base(source)
+	for {
+		if labels, ok := o.Labels[name]; ok {
+			return labels
+		}
+		ext := filepath.Ext(name)
+		if ext == "" {
+			break
+		}
+		name = strings.TrimSuffix(name, ext)
+	}
+	if o.DefaultLabel != "" {
+		return []string{o.DefaultLabel}
+	}
+	return nil
+}
+
+// genre возвращает жанр документа для отчёта: его первую метку.
+func (o Options) genre(source string) string {
+	if labels := o.labels(source); len(labels) > 0 {
+		return labels[0]
+	}
+	return ""
+}
+
+// fastTextPrefix собирает префикс «__label__a __label__b ». Пробелы
+// внутри меток заменяются на _, как требует fastText.
+func fastTextPrefix(labels []string) string {
+	var b strings.Builder
+	for _, label := range labels {
+		b.WriteString("__label__")
+		b.WriteString(strings.Join(strings.Fields(label), "_"))
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// wrapWords делит строку на части не длиннее limit слов.
+func wrapWords(line string, limit int) []string {
+	words := strings.Fields(line)
+	if len(words) <= limit {
+		return []string{line}
+	}
+	var parts []string
+	for len(words) > 0 {
+		n := min(limit, len(words))
+		parts = append(parts, strings.Join(words[:n], " "))
+		words = words[n:]
+	}
+	return parts
+}
@@ -0,0 +1,116 @@
+package writer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// Состояния лемматизации документа.
+const (
+	LemmatizationOff  = "off"  // лемматизация выключена
+	LemmatizationDone = "done" // текст лемматизирован mystem
+)
+
+// Metadata — запись файла метаданных о документе. Поля источника
+// заполняет обработчик, поля вывода — писатель.
+type Metadata struct {
+	File  string `json:"file,omitempty"` // часть вывода при делении или разбиении
+	Line  uint64 `json:"line"`           // первая строка документа в выводе, с 1
+	Lines uint64 `json:"lines"`          // строк документа в выводе
+	Bytes int64  `json:"bytes"`          // байт документа в выводе
+
+	Source        string `json:"source"`
+	SourceBytes   int64  `json:"source_bytes"` // распакованный размер исходного файла
+	SourceLines   int64  `json:"source_lines"`
+	Language      string `json:"language"`
+	Encoding      string `json:"encoding"`
+	Corrupted     uint64 `json:"corrupted_lines"`
+	Lemmatization string `json:"lemmatization"`
+	SHA256        string `json:"sha256"` // хеш строк документа в выводе
+}
+
+// metadataWriter пишет метаданные в JSON Lines: запись на документ, в
+// порядке строк вывода. Документы без строк в выводе не записываются.
+type metadataWriter struct {
+	file   *os.File
+	buf    *bufio.Writer
+	offset int64
+}
+
+func openMetadata(path string, resume bool, bufferSize int) (*metadataWriter, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if resume {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata file: %v", err)
+	}
+	m := &metadataWriter{file: file, buf: bufio.NewWriterSize(file, bufferSize)}
+	if resume {
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		m.offset = info.Size()
+	}
+	return m, nil
+}
+
+func (m *metadataWriter) write(meta Metadata) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := m.buf.Write(data); err != nil {
+		return fmt.Errorf("failed to write metadata: %v", err)
+	}
+	m.offset += int64(len(data))
+	return nil
+}
+
+// sync сбрасывает записи на диск перед снимком журнала.
+func (m *metadataWriter) sync() error {
+	if err := m.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write metadata: %v", err)
+	}
+	return m.file.Sync()
+}
+
+func (m *metadataWriter) Close() error {
+	err := m.buf.Flush()
+	if cerr := m.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write metadata: %v", err)
+	}
+	return nil
+}
+
+// hashingWriter пишет строки документа и считает их хеш.
+type hashingWriter struct {
+	io.StringWriter
+	hash hash.Hash
+}
+
+func newHashingWriter(w io.StringWriter) *hashingWriter {
+	return &hashingWriter{StringWriter: w, hash: sha256.New()}
+}
+
+func (h *hashingWriter) WriteString(s string) (int, error) {
+	io.WriteString(h.hash, s)
+	return h.StringWriter.WriteString(s)
+}
+
+func (h *hashingWriter) sum() string {
+	return hex.EncodeToString(h.hash.Sum(nil))
+}
@@ -0,0 +1,268 @@
+package writer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression — сжатие выходных файлов.
+type Compression string
+
+const (
+	CompressionNone Compression = "none"
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+)
+
+// ParseCompression проверяет имя способа сжатия; пустое имя — без сжатия.
+func ParseCompression(s string) (Compression, error) {
+	switch compression := Compression(s); compression {
+	case "":
+		return CompressionNone, nil
+	case CompressionNone, CompressionGzip, CompressionZstd:
+		return compression, nil
+	}
+	return "", fmt.Errorf("unknown compression %q: expected none|gzip|zstd", s)
+}
+
+func (c Compression) extension() string {
+	switch c {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// Sharding задаёт деление вывода на части и их сжатие.
+type Sharding struct {
+	MaxBytes    int64 // предел несжатого размера части; 0 — без предела
+	MaxDocs     int   // предел документов в части; 0 — без предела
+	Compression Compression
+}
+
+// Enabled сообщает, пишется ли вывод частями или со сжатием: тогда
+// вместо одного файла пишутся части и манифест.
+func (s Sharding) Enabled() bool {
+	return s.MaxBytes > 0 || s.MaxDocs > 0 || (s.Compression != "" && s.Compression != CompressionNone)
+}
+
+func (s Sharding) split() bool {
+	return s.MaxBytes > 0 || s.MaxDocs > 0
+}
+
+// ShardInfo — запись манифеста о части вывода.
+type ShardInfo struct {
+	File      string `json:"file"`
+	Documents uint64 `json:"documents"`
+	Lines     uint64 `json:"lines"`
+	Bytes     uint64 `json:"bytes"` // несжатый размер
+	Size      int64  `json:"size"`  // размер файла на диске
+	SHA256    string `json:"sha256"`
+}
+
+// Manifest — список частей вывода.
+type Manifest struct {
+	Created     time.Time   `json:"created"`
+	Compression Compression `json:"compression"`
+	Documents   uint64      `json:"documents"`
+	Lines       uint64      `json:"lines"`
+	Bytes       uint64      `json:"bytes"`
+	Shards      []ShardInfo `json:"shards"`
+}
+
+// shardWriter пишет вывод частями. Каждая часть пишется во временный
+// файл рядом и переименовывается, только когда полностью записана, так
+// что под итоговым именем никогда не лежит недописанная часть.
+type shardWriter struct {
+	base       string // путь вывода без расширений
+	ext        string // расширение вывода, например ".txt"
+	bufferSize int
+	sharding   Sharding
+	manifest   Manifest
+
+	current *shard
+}
+
+type shard struct {
+	info ShardInfo
+	path string
+	tmp  *os.File
+	hash hash.Hash
+	comp io.WriteCloser // nil — без сжатия
+	buf  *bufio.Writer
+}
+
+func newShardWriter(output string, bufferSize int, sharding Sharding) *shardWriter {
+	if sharding.Compression == "" {
+		sharding.Compression = CompressionNone
+	}
+	output = strings.TrimSuffix(output, sharding.Compression.extension())
+	ext := filepath.Ext(output)
+	return &shardWriter{
+		base:       strings.TrimSuffix(output, ext),
+		ext:        ext,
+		bufferSize: bufferSize,
+		sharding:   sharding,
+		manifest:   Manifest{Compression: sharding.Compression},
+	}
+}
+
+// ManifestPath возвращает путь манифеста для вывода output.
+func ManifestPath(output string, compression Compression) string {
+	output = strings.TrimSuffix(output, compression.extension())
+	return strings.TrimSuffix(output, filepath.Ext(output)) + ".manifest.json"
+}
+
+// beginDocument начинает новую часть, если текущая заполнена.
+func (s *shardWriter) beginDocument() error {
+	if s.current != nil && s.full() {
+		if err := s.finish(); err != nil {
+			return err
+		}
+	}
+	if s.current == nil {
+		return s.open()
+	}
+	return nil
+}
+
+func (s *shardWriter) full() bool {
+	info := s.current.info
+	return (s.sharding.MaxDocs > 0 && info.Documents >= uint64(s.sharding.MaxDocs)) ||
+		(s.sharding.MaxBytes > 0 && info.Bytes >= uint64(s.sharding.MaxBytes))
+}
+
+// endDocument учитывает записанный документ из lines строк.
+func (s *shardWriter) endDocument(lines uint64) {
+	s.current.info.Documents++
+	s.current.info.Lines += lines
+}
+
+func (s *shardWriter) WriteString(text string) (int, error) {
+	n, err := s.current.buf.WriteString(text)
+	s.current.info.Bytes += uint64(n)
+	return n, err
+}
+
+func (s *shardWriter) open() error {
+	name := s.base + s.ext + s.sharding.Compression.extension()
+	if s.sharding.split() {
+		name = fmt.Sprintf("%s-%05d%s%s", s.base, len(s.manifest.Shards)+1, s.ext, s.sharding.Compression.extension())
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create shard: %v", err)
+	}
+
+	sh := &shard{path: name, tmp: tmp, hash: sha256.New()}
+	sh.info.File = filepath.Base(name)
+	var dst io.Writer = io.MultiWriter(tmp, sh.hash)
+	switch s.sharding.Compression {
+	case CompressionGzip:
+		sh.comp = gzip.NewWriter(dst)
+	case CompressionZstd:
+		if sh.comp, err = zstd.NewWriter(dst); err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return fmt.Errorf("failed to start zstd: %v", err)
+		}
+	}
+	if sh.comp != nil {
+		dst = sh.comp
+	}
+	sh.buf = bufio.NewWriterSize(dst, s.bufferSize)
+	s.current = sh
+	return nil
+}
+
+// finish дописывает текущую часть и переименовывает её в итоговое имя.
+func (s *shardWriter) finish() error {
+	sh := s.current
+	s.current = nil
+	err := sh.buf.Flush()
+	if err == nil && sh.comp != nil {
+		err = sh.comp.Close()
+	}
+	if err == nil {
+		err = sh.tmp.Sync()
+	}
+	if err == nil {
+		err = sh.tmp.Chmod(0o644)
+	}
+	if cerr := sh.tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(sh.tmp.Name(), sh.path)
+	}
+	if err != nil {
+		os.Remove(sh.tmp.Name())
+		return fmt.Errorf("failed to finish shard %s: %v", sh.path, err)
+	}
+
+	if info, statErr := os.Stat(sh.path); statErr == nil {
+		sh.info.Size = info.Size()
+	}
+	sh.info.SHA256 = hex.EncodeToString(sh.hash.Sum(nil))
+	s.manifest.Shards = append(s.manifest.Shards, sh.info)
+	s.manifest.Documents += sh.info.Documents
+	s.manifest.Lines += sh.info.Lines
+	s.manifest.Bytes += sh.info.Bytes
+	return nil
+}
+
+// Close завершает последнюю часть и атомарно пишет манифест; без деления
+// и сжатия (только разбиение на выборки) манифест не нужен.
+func (s *shardWriter) Close() error {
+	// Пустой вывод — тоже файл: инструменты ждут, что он существует
+	if s.current == nil && len(s.manifest.Shards) == 0 {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.current != nil {
+		if err := s.finish(); err != nil {
+			return err
+		}
+	}
+	if !s.sharding.Enabled() {
+		return nil
+	}
+	s.manifest.Created = time.Now().UTC()
+	data, err := json.MarshalIndent(s.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := s.base + ".manifest.json"
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
+
+// discard удаляет недописанную часть после ошибки.
+func (s *shardWriter) discard() {
+	if s.current != nil {
+		s.current.tmp.Close()
+		os.Remove(s.current.tmp.Name())
+		s.current = nil
+	}
+}
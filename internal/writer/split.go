@@ -0,0 +1,76 @@
+package writer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"path/filepath"
+	"strings"
+)
+
+// Имена частей разбиения в порядке ratios.
+var splitNames = [3]string{"train", "valid", "test"}
+
+// Split распределяет документы между обучающей, проверочной и тестовой
+// выборками по хешу идентификатора документа (имени файла). Решение не
+// зависит от порядка обработки и от остальных файлов корпуса, поэтому
+// при повторных запусках и пополнении корпуса документы не переходят из
+// выборки в выборку.
+type Split struct {
+	bounds [2]float64 // верхние границы train и valid на отрезке [0, 1)
+	seed   int64
+}
+
+// NewSplit создаёт разбиение по долям train, valid, test (нормируются
+// к сумме). seed меняет разбиение целиком.
+func NewSplit(ratios []float64, seed int64) (*Split, error) {
+	if len(ratios) != 3 {
+		return nil, fmt.Errorf("split needs three ratios (train,valid,test), got %d", len(ratios))
+	}
+	var sum float64
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, fmt.Errorf("split ratios must not be negative: %v", ratios)
+		}
+		sum += r
+	}
+	if sum == 0 {
+		return nil, fmt.Errorf("split ratios sum to zero")
+	}
+	return &Split{
+		bounds: [2]float64{ratios[0] / sum, (ratios[0] + ratios[1]) / sum},
+		seed:   seed,
+	}, nil
+}
+
+// Assign возвращает номер выборки документа: 0 — train, 1 — valid, 2 — test.
+func (s *Split) Assign(source string) int {
+	h := fnv.New64a()
+	var seed [8]byte
+	binary.LittleEndian.PutUint64(seed[:], uint64(s.seed))
+	h.Write(seed[:])
+	h.Write([]byte(DocumentID(source)))
+	u := float64(h.Sum64()>>11) / (1 << 53)
+	switch {
+	case u < s.bounds[0]:
+		return 0
+	case u < s.bounds[1]:
+		return 1
+	}
+	return 2
+}
+
+// DocumentID возвращает идентификатор документа: имя файла без каталога,
+// чтобы разбиение не менялось при переносе корпуса.
+func DocumentID(source string) string {
+	return filepath.Base(source)
+}
+
+// splitOutput возвращает путь вывода выборки: output.txt → output.train.txt
+// (расширение сжатия остаётся в конце: output.train.txt.gz).
+func splitOutput(output, name string, compression Compression) string {
+	suffix := compression.extension()
+	output = strings.TrimSuffix(output, suffix)
+	ext := filepath.Ext(output)
+	return strings.TrimSuffix(output, ext) + "." + name + ext + suffix
+}
@@ -0,0 +1,76 @@
+package writer
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/terratensor/text2glove/internal/vocab"
+)
+
+// Subsampler отбрасывает частые слова, как subsampling в word2vec:
+// слово с частотой f из N остаётся с вероятностью
+// (sqrt(f/(sample*N)) + 1) * sample*N/f.
+type Subsampler struct {
+	keep    map[string]float64 // вероятность сохранить слово, только для < 1
+	seed    uint64
+	dropped atomic.Uint64
+}
+
+// NewSubsampler строит таблицу вероятностей по словарю частот.
+func NewSubsampler(entries []vocab.Entry, sample float64, seed int64) *Subsampler {
+	var total uint64
+	for _, e := range entries {
+		total += e.Count
+	}
+
+	s := &Subsampler{keep: make(map[string]float64), seed: uint64(seed)}
+	threshold := sample * float64(total)
+	for _, e := range entries {
+		f := float64(e.Count)
+		if p := (math.Sqrt(f/threshold) + 1) * threshold / f; p < 1 {
+			s.keep[e.Word] = p
+		}
+	}
+	return s
+}
+
+// Apply прореживает строку line документа source. Решение для каждого
+// токена зависит только от зерна, документа и позиции токена, поэтому
+// результат воспроизводим при любом порядке работы рабочих.
+func (s *Subsampler) Apply(source string, lineNum int, line string) string {
+	tokens := strings.Fields(line)
+	h := fnv.New64a()
+	h.Write([]byte(strconv.FormatUint(s.seed, 10)))
+	h.Write([]byte{0})
+	h.Write([]byte(source))
+	state := h.Sum64() ^ uint64(lineNum)*0x9e3779b97f4a7c15
+
+	kept := tokens[:0]
+	var dropped uint64
+	for _, token := range tokens {
+		state = splitmix64(state)
+		if p, ok := s.keep[token]; ok && float64(state>>11)/(1<<53) >= p {
+			dropped++
+			continue
+		}
+		kept = append(kept, token)
+	}
+	s.dropped.Add(dropped)
+	return strings.Join(kept, " ")
+}
+
+// Dropped возвращает число отброшенных токенов.
+func (s *Subsampler) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	z := x
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
@@ -2,7 +2,9 @@ package writer
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"sync/atomic"
 	"time"
@@ -13,53 +15,401 @@ type Stats struct {
 	Bytes     uint64
 	Duration  time.Duration
 	Corrupted uint64 // Добавляем счетчик битых файлов
+	Skipped   uint64 // документы без меток в формате fasttext
+	Dropped   uint64 // токены, отброшенные прореживанием частых слов
+	Shards    uint64 // частей вывода при делении или сжатии
+	Empty     uint64 // документы, от которых после очистки ничего не осталось
+	Splits    []SplitStats
+}
+
+// SplitStats — размер выборки при разбиении на train/valid/test.
+type SplitStats struct {
+	Name      string
+	Documents uint64
+	Lines     uint64
+}
+
+// Layout задаёт порядок документов в выводе и его раскладку по файлам.
+type Layout struct {
+	Ordered       bool     // писать документы в порядке Seq
+	ReorderBuffer int      // сколько документов может ждать своей очереди
+	Sharding      Sharding // деление вывода на части и сжатие
+	Split         *Split   // nil — без разбиения на train/valid/test
+	MetadataPath  string   // файл метаданных документов (JSON Lines); пусто — не пишется
+}
+
+// Checkpointer — журнал записанного вывода для --resume.
+type Checkpointer interface {
+	// Resume возвращает длину и число строк вывода, после которых он
+	// дописывается; ok == false — вывод пишется заново.
+	Resume() (offset int64, lines uint64, ok bool)
+	// Written отмечает записанный документ; due — пора сохранить снимок.
+	Written(source string) (due bool)
+	// Checkpoint сохраняет снимок сброшенного на диск вывода.
+	Checkpoint(output io.ReaderAt, offset int64, lines uint64, metadata int64, interrupted bool) error
+}
+
+// ErrorSink принимает ошибки записи, после которых обработка
+// останавливается.
+type ErrorSink interface {
+	Abort(source string, err error)
+}
+
+// Collector собирает статистику записанных строк для отчёта.
+type Collector interface {
+	Add(lines []string, language, genre string)
 }
 
 type ResultWriter struct {
 	filePath   string
 	bufferSize int
+	options    Options
+	layout     Layout
+	journal    Checkpointer // nil — журнал не ведётся
+	errors     ErrorSink    // nil — ошибки только печатаются
+	report     Collector    // nil — статистика для отчёта не собирается
 	totalLines atomic.Uint64
 	totalBytes atomic.Uint64
 	corrupted  atomic.Uint64 // Счетчик битых файлов
+	skipped    atomic.Uint64
+	empty      atomic.Uint64
 	startTime  time.Time
+	window     chan struct{} // места в буфере упорядочивания; nil — порядок не важен
+	shards     atomic.Uint64 // записано частей вывода
+	splitDocs  [3]atomic.Uint64
+	splitLines [3]atomic.Uint64
 }
 
-func New(filePath string, bufferSize int) *ResultWriter {
-	return &ResultWriter{
+// DefaultReorderBuffer — размер буфера упорядочивания по умолчанию.
+const DefaultReorderBuffer = 1024
+
+// New создаёт писатель. journal, errors и report могут быть nil.
+func New(filePath string, bufferSize int, options Options, layout Layout, journal Checkpointer, errors ErrorSink, report Collector) *ResultWriter {
+	if options.Format == "" {
+		options.Format = FormatGloVe
+	}
+	w := &ResultWriter{
 		filePath:   filePath,
 		bufferSize: bufferSize,
+		options:    options,
+		layout:     layout,
+		journal:    journal,
+		errors:     errors,
+		report:     report,
 		startTime:  time.Now(),
 	}
+	if layout.Ordered {
+		if layout.ReorderBuffer <= 0 {
+			layout.ReorderBuffer = DefaultReorderBuffer
+		}
+		w.window = make(chan struct{}, layout.ReorderBuffer)
+	}
+	return w
+}
+
+// Reserve занимает место в буфере упорядочивания перед выдачей файла
+// рабочему; место освобождается, когда документ записан. Так рабочие не
+// уходят вперёд медленного документа больше чем на ReorderBuffer файлов.
+// Без упорядочивания Reserve ничего не делает. false — ctx отменён.
+func (w *ResultWriter) Reserve(ctx context.Context) bool {
+	if w.window == nil {
+		return true
+	}
+	select {
+	case w.window <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
-func (w *ResultWriter) Write(textChan <-chan string) {
+// Write пишет документы из docChan, пока канал не закрыт. После отмены
+// ctx писатель дописывает уже готовые документы, сбрасывает буфер и
+// сохраняет журнал с отметкой о прерывании.
+func (w *ResultWriter) Write(ctx context.Context, docChan <-chan Document) {
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Printf("\x1b[31mWriter panic: %v\x1b[0m\n", r)
 		}
 	}()
 
-	file, err := os.Create(w.filePath)
+	if w.layout.Sharding.Enabled() || w.layout.Split != nil {
+		w.writeParts(docChan)
+		return
+	}
+
+	// При возобновлении вывод уже обрезан по журналу и дописывается
+	flags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	var offset int64
+	var lines uint64
+	j := w.journal
+	if j != nil {
+		if resumeOffset, resumeLines, ok := j.Resume(); ok {
+			flags = os.O_RDWR | os.O_CREATE | os.O_APPEND
+			offset, lines = resumeOffset, resumeLines
+		}
+	}
+	file, err := os.OpenFile(w.filePath, flags, 0o644)
 	if err != nil {
-		fmt.Printf("\x1b[31mFailed to create output file: %v\x1b[0m\n", err)
+		w.abort(w.filePath, fmt.Errorf("failed to create output file: %v", err))
+		w.drain(docChan)
 		return
 	}
 	defer file.Close()
+	meta, err := w.openMetadata()
+	if err != nil {
+		w.abort(w.layout.MetadataPath, err)
+		w.drain(docChan)
+		return
+	}
 
 	writer := bufio.NewWriterSize(file, w.bufferSize)
 	defer writer.Flush()
 
-	for text := range textChan {
-		if text == "" {
+	// После ошибки записи (broken) документы только вычитываются из
+	// канала, а журнал остаётся на последнем согласованном снимке.
+	var broken bool
+	checkpoint := func(interrupted bool) {
+		if err := writer.Flush(); err != nil {
+			w.abort(w.filePath, err)
+			broken = true
+			return
+		}
+		if err := file.Sync(); err != nil {
+			w.abort(w.filePath, fmt.Errorf("failed to sync output: %v", err))
+			broken = true
+			return
+		}
+		var metadata int64
+		if meta != nil {
+			if err := meta.sync(); err != nil {
+				w.abort(w.layout.MetadataPath, err)
+				broken = true
+				return
+			}
+			metadata = meta.offset
+		}
+		if err := j.Checkpoint(file, offset, lines, metadata, interrupted); err != nil {
+			fmt.Printf("\x1b[31mJournal error: %v\x1b[0m\n", err)
+		}
+	}
+
+	handle := func(doc Document) {
+		if broken || doc.Failed {
+			return
+		}
+		if len(doc.Lines) == 0 {
+			w.empty.Add(1)
+		} else {
+			if err := w.writeTracked(writer, doc, &offset, &lines, meta, "", lines+1); err != nil {
+				w.abort(doc.Source, err)
+				broken = true
+				return
+			}
+		}
+		if j == nil {
+			return
+		}
+		if j.Written(doc.Source) {
+			checkpoint(false)
+		}
+	}
+
+	w.each(docChan, handle)
+
+	if j != nil && !broken {
+		checkpoint(ctx.Err() != nil)
+	}
+	if meta != nil {
+		if err := meta.Close(); err != nil && !broken {
+			w.abort(w.layout.MetadataPath, err)
+		}
+	}
+}
+
+// each передаёт документы из docChan в handle: по мере поступления или,
+// в упорядоченном режиме, по возрастанию Seq — документ ждёт в reorder,
+// пока не обработаны все документы с меньшими номерами.
+func (w *ResultWriter) each(docChan <-chan Document, handle func(Document)) {
+	reorder := make(map[int]Document)
+	next := 0
+	for doc := range docChan {
+		if w.window == nil {
+			handle(doc)
+			continue
+		}
+		reorder[doc.Seq] = doc
+		for {
+			ready, ok := reorder[next]
+			if !ok {
+				break
+			}
+			delete(reorder, next)
+			next++
+			handle(ready)
+			<-w.window
+		}
+	}
+	// Документы после пропуска в очереди (обработка прервана) не пишутся:
+	// тогда журнал покрывает непрерывный префикс входа, и --resume
+	// продолжает вывод в том же порядке
+}
+
+// writeParts пишет документы частями со сжатием и манифестом и/или
+// раскладывает их по выборкам train/valid/test. Журнал в этом режиме
+// не ведётся.
+func (w *ResultWriter) writeParts(docChan <-chan Document) {
+	outputs := []*shardWriter{newShardWriter(w.filePath, w.bufferSize, w.layout.Sharding)}
+	if w.layout.Split != nil {
+		outputs = outputs[:0]
+		for _, name := range splitNames {
+			path := splitOutput(w.filePath, name, w.layout.Sharding.Compression)
+			outputs = append(outputs, newShardWriter(path, w.bufferSize, w.layout.Sharding))
+		}
+	}
+
+	meta, err := w.openMetadata()
+	if err != nil {
+		w.abort(w.layout.MetadataPath, err)
+		w.drain(docChan)
+		return
+	}
+
+	var offset int64
+	var lines uint64
+	var broken bool
+	w.each(docChan, func(doc Document) {
+		if broken || doc.Failed {
+			return
+		}
+		if len(doc.Lines) == 0 {
+			w.empty.Add(1)
+			return
+		}
+		part := 0
+		if w.layout.Split != nil {
+			part = w.layout.Split.Assign(doc.Source)
+		}
+		out := outputs[part]
+		if err := out.beginDocument(); err != nil {
+			w.abort(doc.Source, err)
+			broken = true
+			return
+		}
+		before := lines
+		current := out.current.info
+		if err := w.writeTracked(out, doc, &offset, &lines, meta, current.File, current.Lines+1); err != nil {
+			w.abort(doc.Source, err)
+			broken = true
+			return
+		}
+		out.endDocument(lines - before)
+		if lines > before {
+			w.splitDocs[part].Add(1)
+			w.splitLines[part].Add(lines - before)
+		}
+	})
+
+	for _, out := range outputs {
+		if broken {
+			out.discard()
+			continue
+		}
+		if err := out.Close(); err != nil {
+			w.abort(w.filePath, err)
+			out.discard()
+			broken = true
 			continue
 		}
-		_, err := writer.WriteString(text + "\n")
-		if err != nil {
-			fmt.Printf("\x1b[31mWrite error: %v\x1b[0m\n", err)
+		w.shards.Add(uint64(len(out.manifest.Shards)))
+	}
+	if meta != nil {
+		if err := meta.Close(); err != nil && !broken {
+			w.abort(w.layout.MetadataPath, err)
+		}
+	}
+}
+
+// openMetadata открывает файл метаданных, если он задан; при
+// возобновлении файл уже обрезан по журналу и дописывается.
+func (w *ResultWriter) openMetadata() (*metadataWriter, error) {
+	if w.layout.MetadataPath == "" {
+		return nil, nil
+	}
+	var resume bool
+	if w.journal != nil {
+		_, _, resume = w.journal.Resume()
+	}
+	return openMetadata(w.layout.MetadataPath, resume, w.bufferSize)
+}
+
+// drain вычитывает документы после ошибки: рабочие не должны зависнуть
+// на отправке.
+func (w *ResultWriter) drain(docChan <-chan Document) {
+	for range docChan {
+	}
+}
+
+// writeTracked пишет документ и, если ведутся метаданные, запись о нём.
+// file и first — часть вывода и номер первой строки документа в ней.
+func (w *ResultWriter) writeTracked(out io.StringWriter, doc Document, offset *int64, lines *uint64, meta *metadataWriter, file string, first uint64) error {
+	if meta == nil || doc.Meta == nil {
+		return w.writeDocument(out, doc, offset, lines)
+	}
+	hashed := newHashingWriter(out)
+	startOffset, startLines := *offset, *lines
+	if err := w.writeDocument(hashed, doc, offset, lines); err != nil {
+		return err
+	}
+	if *lines == startLines {
+		return nil // документ пропущен целиком: строк, к которым привязать запись, нет
+	}
+	record := *doc.Meta
+	record.File = file
+	record.Line = first
+	record.Lines = *lines - startLines
+	record.Bytes = *offset - startOffset
+	record.SHA256 = hashed.sum()
+	return meta.write(record)
+}
+
+// writeDocument пишет строки документа и сдвигает offset и lines.
+func (w *ResultWriter) writeDocument(writer io.StringWriter, doc Document, offset *int64, lines *uint64) error {
+	rendered, ok := w.options.render(doc)
+	if !ok {
+		w.skipped.Add(1)
+		return nil
+	}
+	written := rendered[:0]
+	for _, line := range rendered {
+		if line == "" {
 			continue
 		}
+		if _, err := writer.WriteString(line + "\n"); err != nil {
+			return err
+		}
 		w.totalLines.Add(1)
-		w.totalBytes.Add(uint64(len(text) + 1)) // +1 for newline
+		w.totalBytes.Add(uint64(len(line) + 1)) // +1 for newline
+		*offset += int64(len(line) + 1)
+		*lines++
+		written = append(written, line)
+	}
+	if w.report != nil && len(written) > 0 {
+		var language string
+		if doc.Meta != nil {
+			language = doc.Meta.Language
+		}
+		w.report.Add(written, language, w.options.genre(doc.Source))
+	}
+	return nil
+}
+
+// abort сообщает об ошибке записи: продолжать после неё нельзя, поэтому
+// обработка останавливается при любой политике ошибок.
+func (w *ResultWriter) abort(source string, err error) {
+	fmt.Printf("\r\x1b[31mWrite error:\x1b[0m %v\n", err)
+	if w.errors != nil {
+		w.errors.Abort(source, err)
 	}
 }
 
@@ -68,11 +418,39 @@ func (w *ResultWriter) IncrementCorrupted() {
 	w.corrupted.Add(1)
 }
 
+// AddCorrupted добавляет n битых строк обработанного файла.
+func (w *ResultWriter) AddCorrupted(n uint64) {
+	w.corrupted.Add(n)
+}
+
 func (w *ResultWriter) GetStats() Stats {
 	return Stats{
 		Lines:     w.totalLines.Load(),
 		Bytes:     w.totalBytes.Load(),
 		Duration:  time.Since(w.startTime),
 		Corrupted: w.corrupted.Load(),
+		Skipped:   w.skipped.Load(),
+		Dropped:   w.dropped(),
+		Shards:    w.shards.Load(),
+		Empty:     w.empty.Load(),
+		Splits:    w.splitStats(),
+	}
+}
+
+func (w *ResultWriter) splitStats() []SplitStats {
+	if w.layout.Split == nil {
+		return nil
+	}
+	stats := make([]SplitStats, len(splitNames))
+	for i, name := range splitNames {
+		stats[i] = SplitStats{Name: name, Documents: w.splitDocs[i].Load(), Lines: w.splitLines[i].Load()}
+	}
+	return stats
+}
+
+func (w *ResultWriter) dropped() uint64 {
+	if w.options.Subsampler == nil {
+		return 0
 	}
+	return w.options.Subsampler.Dropped()
 }
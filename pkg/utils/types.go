@@ -6,15 +6,87 @@ type Config struct {
 	WorkersCount int    `yaml:"workers"`
 	BufferSize   int    `yaml:"buffer_size"`
 	ReportEvery  int    `yaml:"report_every"`
+	OutputMode   string `yaml:"output_mode"`   // document | sentence
+	OutputFormat string `yaml:"output_format"` // glove | fasttext | word2vec | linesentence
+	Metadata     string `yaml:"metadata"`      // метаданные документов в JSON Lines; пусто — не пишутся
+
+	Sharding struct {
+		MaxMB       int    `yaml:"max_mb"`      // размер части несжатого текста, МБ; 0 — один файл
+		MaxDocs     int    `yaml:"max_docs"`    // документов в части; 0 — без предела
+		Compression string `yaml:"compression"` // none | gzip | zstd
+	} `yaml:"sharding"`
+
+	Split struct {
+		Ratios []float64 `yaml:"ratios"` // доли train, valid, test; пусто — без разбиения
+		Seed   int64     `yaml:"seed"`   // зерно хеша документа
+	} `yaml:"split"`
+
+	Report struct {
+		JSON string `yaml:"json"` // отчёт о корпусе в JSON
+		HTML string `yaml:"html"` // отчёт о корпусе HTML-страницей
+		Top  int    `yaml:"top"`  // сколько частых токенов показать
+	} `yaml:"report"`
+
+	Ordering struct {
+		Enable        bool `yaml:"enable"`         // писать документы в порядке входных файлов
+		ReorderBuffer int  `yaml:"reorder_buffer"` // сколько документов может ждать очереди
+	} `yaml:"ordering"`
+
+	Format struct {
+		LabelsFile    string `yaml:"labels_file"`     // метки документов для fasttext
+		DefaultLabel  string `yaml:"default_label"`   // метка документов без меток
+		MaxLineLength int    `yaml:"max_line_length"` // предел слов в строке
+	} `yaml:"format"`
 
 	Cleaner struct {
 		Mode             string `yaml:"mode" default:"unicode_letters_and_numbers"`
 		KeepNumbers      bool   `yaml:"keep_numbers" default:"true"`
 		KeepRomanNumbers bool   `yaml:"keep_roman_numbers" default:"true"`
+		Numbers          string `yaml:"numbers"`       // keep | drop | placeholder | shape | bucket
+		RomanNumbers     string `yaml:"roman_numbers"` // то же для римских чисел
 		Normalize        bool   `yaml:"normalize"`
 		PreserveSpaces   bool   `yaml:"preserve_spaces"`
+		Slavonic         bool   `yaml:"slavonic"`      // церковнославянская нормализация
+		SlavonicDict     string `yaml:"slavonic_dict"` // доп. словарь сокращений под титлом
 	} `yaml:"cleaner"`
 
+	Hyphenation struct {
+		Enable     bool   `yaml:"enable"`     // склеивать слова, разорванные переносом
+		Dictionary string `yaml:"dictionary"` // словарь слов для проверки склейки
+		MaxWords   int    `yaml:"max_words"`  // предел таблицы частот встреченных слов
+	} `yaml:"hyphenation"`
+
+	Filter struct {
+		Stopwords     []string `yaml:"stopwords"`      // встроенные списки: ru, en
+		StopwordsFile string   `yaml:"stopwords_file"` // доп. стоп-слова, слово в строке
+		MinLength     int      `yaml:"min_length"`     // минимальная длина токена в рунах
+		MaxLength     int      `yaml:"max_length"`     // максимальная длина токена в рунах
+	} `yaml:"filter"`
+
+	Subsampling struct {
+		Vocab  string  `yaml:"vocab"`  // словарь частот (команда vocab)
+		Sample float64 `yaml:"sample"` // порог прореживания; 0 — выключено
+		Seed   int64   `yaml:"seed"`   // зерно генератора
+	} `yaml:"subsampling"`
+
+	Journal struct {
+		Enable   bool   `yaml:"enable"`   // вести журнал обработанных файлов
+		Path     string `yaml:"path"`     // путь журнала; пусто — <output>.journal
+		Interval int    `yaml:"interval"` // как часто сохранять журнал, секунды
+		Resume   bool   `yaml:"-"`        // продолжить прерванный запуск (только флаг)
+	} `yaml:"journal"`
+
+	Errors struct {
+		Policy    string `yaml:"policy"`     // skip | fail-fast | retry
+		Retries   int    `yaml:"retries"`    // повторов файла при политике retry
+		MaxErrors int    `yaml:"max_errors"` // порог ошибок для остановки; 0 — без порога
+		Report    string `yaml:"report"`     // отчёт об ошибках в JSON Lines
+	} `yaml:"errors"`
+
+	HTTP struct {
+		Listen string `yaml:"listen"` // адрес /metrics, /status и /debug/pprof; пусто — не запускать
+	} `yaml:"http"`
+
 	Lemmatization struct {
 		Enable      bool   `yaml:"enable"`
 		MystemPath  string `yaml:"mystem_path"`
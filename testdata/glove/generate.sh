@@ -0,0 +1,25 @@
+#!/bin/sh
+# Пересоздаёт эталоны cooccur из этого каталога немодифицированной
+# программой cooccur из GloVe.
+#
+#   testdata/glove/generate.sh /path/to/GloVe/build/cooccur
+set -eu
+
+if [ $# -ne 1 ]; then
+    echo "usage: $0 /path/to/GloVe/build/cooccur" >&2
+    exit 2
+fi
+cooccur=$(cd "$(dirname "$1")" && pwd)/$(basename "$1")
+dir=$(cd "$(dirname "$0")" && pwd)
+
+# overflow_*.bin пишутся в текущий каталог
+tmp=$(mktemp -d)
+trap 'rm -rf "$tmp"' EXIT
+cd "$tmp"
+
+# Окно 2: при большем окне симметричный режим выходит за буфер
+# переполнения (см. README.md)
+"$cooccur" -verbose 0 -vocab-file "$dir/vocab.txt" -window-size 2 -symmetric 1 -distance-weighting 1 \
+    -max-product 20 -overflow-length 200 < "$dir/corpus.txt" > "$dir/cooccurrence.bin"
+"$cooccur" -verbose 0 -vocab-file "$dir/vocab.txt" -window-size 5 -symmetric 0 -distance-weighting 0 \
+    -max-product 20 -overflow-length 200 < "$dir/corpus.txt" > "$dir/cooccurrence.left.bin"